@@ -0,0 +1,527 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	licerrors "example.com/licence-approval/client/pkg/errors"
+	"example.com/licence-approval/client/pkg/licenseclient"
+	"example.com/licence-approval/client/pkg/licensefile"
+	"example.com/licence-approval/client/pkg/settings"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// Коды завершения; на них опираются скрипты развёртывания и CI
+const (
+	exitOK             = 0 // лицензия действует / команда выполнена
+	exitError          = 1 // ошибка конфигурации, сети или сервера
+	exitUsage          = 2 // неверные аргументы
+	exitNotLicensed    = 3 // заявка ожидает решения или ещё не создана
+	exitRejected       = 4 // заявка отклонена
+	exitRevoked        = 5 // лицензия истекла, деактивирована или перенесена
+	exitTimeout        = 6 // время ожидания одобрения истекло
+	exitInvalidLicense = 7 // файл лицензии не прошёл проверку
+)
+
+const usage = `Usage: client [command] [flags]
+
+Commands:
+  activate         check the license, request one if needed and wait for approval (default)
+  status           check the license once
+  request          create a license request without waiting
+  wait             wait until the request is approved or rejected
+  show-key         print the license key of this installation
+  deactivate       release the license activation on this machine
+  verify-file      verify a signed license file offline
+  export-request   write an activation request file for a machine without network access
+  import-response  activate from the response file issued by an administrator
+
+Common flags:
+  --json           print the result as JSON to stdout
+  --max-wait D     give up waiting for approval after D ("unlimited" waits until a decision)
+
+Exit codes:
+  0 licensed / success     4 request rejected
+  1 error                  5 license expired, deactivated or transferred
+  2 usage error            6 timed out waiting for approval
+  3 pending / no license   7 license file is invalid
+
+Run "client <command> -h" for command flags.
+`
+
+var commands = map[string]func(ctx context.Context, c *cli, args []string) int{
+	"activate":        runActivate,
+	"status":          runStatus,
+	"request":         runRequest,
+	"wait":            runWait,
+	"show-key":        runShowKey,
+	"deactivate":      runDeactivate,
+	"verify-file":     runVerifyFile,
+	"export-request":  runExportRequest,
+	"import-response": runImportResponse,
+}
+
+// parseCommand выделяет имя команды. Без команды выполняется activate;
+// старый флаг -deactivate соответствует команде deactivate.
+func parseCommand(args []string) (string, []string) {
+	if len(args) == 0 {
+		return "activate", nil
+	}
+	switch args[0] {
+	case "-deactivate", "--deactivate":
+		return "deactivate", args[1:]
+	case "-h", "-help", "--help", "help":
+		return "help", nil
+	}
+	if len(args[0]) > 0 && args[0][0] == '-' {
+		return "activate", args
+	}
+	return args[0], args[1:]
+}
+
+func runCommand(ctx context.Context, name string, args []string) int {
+	if name == "help" {
+		fmt.Print(usage)
+		return exitOK
+	}
+	run, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n\n%s", name, usage)
+		return exitUsage
+	}
+	c := &cli{name: name}
+	code := run(ctx, c, args)
+	c.stopTracing()
+	return code
+}
+
+// cli — разбор флагов и вывод результата команды
+type cli struct {
+	name string
+	json bool
+
+	fs         *flag.FlagSet
+	configFile string
+	// tracing — провайдер спанов, если включён параметр tracing
+	tracing *sdktrace.TracerProvider
+}
+
+// settingFlag — флаг настройки клиента; флажки (trial, mtls) можно задать без значения
+type settingFlag struct {
+	key    string
+	value  string
+	isBool bool
+}
+
+func (f *settingFlag) String() string     { return f.value }
+func (f *settingFlag) Set(v string) error { f.value = v; return nil }
+func (f *settingFlag) IsBoolFlag() bool   { return f.isBool }
+
+// flags создаёт набор флагов команды: --json, --config и флаги всех настроек
+func (c *cli) flags() *flag.FlagSet {
+	fs := flag.NewFlagSet(c.name, flag.ContinueOnError)
+	fs.BoolVar(&c.json, "json", false, "print the result as JSON")
+	fs.StringVar(&c.configFile, "config", "", "settings `file` (YAML or JSON) instead of the per-user one")
+	for _, s := range settings.Known {
+		fs.Var(&settingFlag{key: s.Key, isBool: s.IsBool()}, s.Flag, s.Usage)
+	}
+	c.fs = fs
+	return fs
+}
+
+// env загружает настройки с учётом флагов, заданных явно
+func (c *cli) env() (*clientEnv, error) {
+	flags := make(map[string]string)
+	c.fs.Visit(func(f *flag.Flag) {
+		if sf, ok := f.Value.(*settingFlag); ok {
+			flags[sf.key] = sf.value
+		}
+	})
+	env, err := loadEnv(c.configFile, flags)
+	if err != nil {
+		return nil, err
+	}
+	configureLogging(env.settings)
+	return env, nil
+}
+
+// parse разбирает флаги; при ошибке возвращает код завершения
+func (c *cli) parse(fs *flag.FlagSet, args []string) (int, bool) {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK, false
+		}
+		return exitUsage, false
+	}
+	return 0, true
+}
+
+// print выводит v как JSON либо текст для человека
+func (c *cli) print(v interface{}, format string, a ...interface{}) {
+	if c.json {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(v)
+		return
+	}
+	fmt.Printf(format+"\n", a...)
+}
+
+// fail сообщает об ошибке и возвращает код завершения
+func (c *cli) fail(code int, err error) int {
+	if c.json {
+		// Код ошибки сервера (problem+json), если ошибка пришла от него
+		var problemCode string
+		if p := licerrors.ProblemOf(err); p != nil {
+			problemCode = p.Code
+		}
+		c.print(struct {
+			Error    string `json:"error"`
+			Code     string `json:"code,omitempty"`
+			ExitCode int    `json:"exit_code"`
+		}{err.Error(), problemCode, code}, "")
+	} else {
+		slog.Error("Command failed", "command", c.name, "error", err)
+	}
+	return code
+}
+
+// client загружает настройки и создаёт клиент лицензий
+func (c *cli) client(generateKey bool, opts ...licenseclient.Option) (*clientEnv, *licenseclient.Client, int) {
+	env, err := c.env()
+	if err != nil {
+		return nil, nil, c.fail(exitError, err)
+	}
+	if err := c.startTracing(env.settings.Tracing); err != nil {
+		return nil, nil, c.fail(exitError, err)
+	}
+	if generateKey {
+		generated, err := env.ensureLicenseKey()
+		if err != nil {
+			return nil, nil, c.fail(exitError, err)
+		}
+		if generated && !c.json {
+			fmt.Printf("Generated License Key: %s\n", env.settings.LicenseKey)
+		}
+	}
+	lc, err := env.newClient(opts...)
+	if errors.Is(err, errNoLicenseKey) {
+		return nil, nil, c.fail(exitNotLicensed, err)
+	}
+	if err != nil {
+		return nil, nil, c.fail(exitError, err)
+	}
+	return env, lc, exitOK
+}
+
+// statusOutput — результат status и wait в формате JSON
+type statusOutput struct {
+	Status       licenseclient.Status `json:"status"`
+	Reason       string               `json:"reason,omitempty"`
+	Message      string               `json:"message"`
+	Licensed     bool                 `json:"licensed"`
+	LicenseKey   string               `json:"license_key"`
+	RequestID    int                  `json:"request_id,omitempty"`
+	ExpiresAt    *time.Time           `json:"expires_at,omitempty"`
+	ServerTime   *time.Time           `json:"server_time,omitempty"`
+	OfflineUntil *time.Time           `json:"offline_until,omitempty"`
+	LicenseFile  string               `json:"license_file,omitempty"`
+}
+
+func newStatusOutput(lc *licenseclient.Client, res *licenseclient.Result) statusOutput {
+	out := statusOutput{
+		Status:     res.Status,
+		Reason:     res.Reason,
+		Message:    res.Message,
+		Licensed:   res.Licensed(),
+		LicenseKey: lc.LicenseKey(),
+		RequestID:  res.RequestID,
+	}
+	if !res.ExpiresAt.IsZero() {
+		out.ExpiresAt = &res.ExpiresAt
+	}
+	if !res.ServerTime.IsZero() {
+		out.ServerTime = &res.ServerTime
+	}
+	if !res.OfflineUntil.IsZero() {
+		out.OfflineUntil = &res.OfflineUntil
+	}
+	return out
+}
+
+// statusExitCode переводит статус лицензии в код завершения
+func statusExitCode(s licenseclient.Status) int {
+	switch s {
+	case licenseclient.StatusActive, licenseclient.StatusOffline:
+		return exitOK
+	case licenseclient.StatusRejected:
+		return exitRejected
+	case licenseclient.StatusExpired, licenseclient.StatusDeactivated, licenseclient.StatusTransferred:
+		return exitRevoked
+	}
+	return exitNotLicensed
+}
+
+// reportStatus выводит статус и при необходимости сохраняет подписанную лицензию
+func (c *cli) reportStatus(ctx context.Context, lc *licenseclient.Client, res *licenseclient.Result, saveTo string) int {
+	out := newStatusOutput(lc, res)
+	if saveTo != "" && res.Status == licenseclient.StatusActive {
+		f, err := lc.License(ctx)
+		if err == nil {
+			err = f.Save(saveTo)
+		}
+		if err != nil {
+			return c.fail(exitError, fmt.Errorf("save license file: %w", err))
+		}
+		out.LicenseFile = saveTo
+	}
+
+	switch {
+	case res.Status == licenseclient.StatusOffline:
+		c.print(out, "License status: offline (server unreachable), valid until %s", res.OfflineUntil.Format(time.RFC3339))
+	case out.LicenseFile != "":
+		c.print(out, "License status: %s (%s)\nLicense saved to %s", res.Status, res.Message, out.LicenseFile)
+	default:
+		c.print(out, "License status: %s (%s)", res.Status, res.Message)
+	}
+	return statusExitCode(res.Status)
+}
+
+func runActivate(ctx context.Context, c *cli, args []string) int {
+	fs := c.flags()
+	if code, ok := c.parse(fs, args); !ok {
+		return code
+	}
+
+	if !c.json {
+		fmt.Println("=== Client Started ===")
+	}
+	env, lc, code := c.client(true)
+	if lc == nil {
+		return code
+	}
+	if !c.json {
+		fmt.Printf("Using License Key: %s\n", env.settings.LicenseKey)
+		if s := env.settings; s.Product != "" {
+			fmt.Printf("Product: %s %s\n", s.Product, s.ProductVersion)
+		}
+		fmt.Println("=== Client is running ===")
+	}
+
+	res, err := lc.Check(ctx)
+	if err != nil {
+		return c.fail(exitError, err)
+	}
+	if !res.Final() {
+		if res.Status == licenseclient.StatusNotActive {
+			slog.Info("License is not active, creating a new license request")
+			req, err := lc.Request(ctx)
+			if err != nil {
+				return c.fail(exitError, err)
+			}
+			if req.Existing {
+				slog.Info("License request already exists, waiting for approval", "license_request_id", req.RequestID)
+			} else {
+				slog.Info("License request created, waiting for approval", "license_request_id", req.RequestID)
+			}
+		} else {
+			slog.Info("License request is pending, waiting for approval")
+		}
+		if res, err = lc.WaitForApproval(ctx); err != nil {
+			return c.waitFailed(err)
+		}
+	}
+
+	code = c.reportStatus(ctx, lc, res, "")
+	if code == exitOK && !c.json {
+		fmt.Println("The client can proceed.")
+		fmt.Println("=== Client Finished ===")
+	}
+	return code
+}
+
+func runStatus(ctx context.Context, c *cli, args []string) int {
+	fs := c.flags()
+	save := fs.String("save", "", "save the signed license to `file` if it is active")
+	if code, ok := c.parse(fs, args); !ok {
+		return code
+	}
+
+	_, lc, code := c.client(false)
+	if lc == nil {
+		return code
+	}
+	res, err := lc.Check(ctx)
+	if err != nil {
+		return c.fail(exitError, err)
+	}
+	return c.reportStatus(ctx, lc, res, *save)
+}
+
+func runRequest(ctx context.Context, c *cli, args []string) int {
+	fs := c.flags()
+	if code, ok := c.parse(fs, args); !ok {
+		return code
+	}
+
+	_, lc, code := c.client(true)
+	if lc == nil {
+		return code
+	}
+	res, err := lc.Check(ctx)
+	if err != nil {
+		return c.fail(exitError, err)
+	}
+	// Лицензия уже действует или решение принято — новая заявка не нужна
+	if res.Status != licenseclient.StatusNotActive {
+		return c.reportStatus(ctx, lc, res, "")
+	}
+
+	req, err := lc.Request(ctx)
+	if err != nil {
+		return c.fail(exitError, err)
+	}
+	out := struct {
+		RequestID  int                  `json:"request_id"`
+		Existing   bool                 `json:"existing"`
+		Status     licenseclient.Status `json:"status,omitempty"`
+		Reason     string               `json:"reason,omitempty"`
+		LicenseKey string               `json:"license_key"`
+	}{req.RequestID, req.Existing, req.Status, req.Reason, lc.LicenseKey()}
+	if req.Existing {
+		c.print(out, "License request already exists with ID %d.", req.RequestID)
+	} else {
+		c.print(out, "License request #%d created.", req.RequestID)
+	}
+	return exitNotLicensed
+}
+
+func runWait(ctx context.Context, c *cli, args []string) int {
+	fs := c.flags()
+	// Прежние имена флагов --max-wait и --poll-interval
+	fs.Var(&settingFlag{key: "max_wait"}, "timeout", "alias for --max-wait")
+	fs.Var(&settingFlag{key: "poll_interval"}, "interval", "alias for --poll-interval")
+	save := fs.String("save", "", "save the signed license to `file` once approved")
+	if code, ok := c.parse(fs, args); !ok {
+		return code
+	}
+
+	_, lc, code := c.client(false)
+	if lc == nil {
+		return code
+	}
+	res, err := lc.WaitForApproval(ctx)
+	if err != nil {
+		return c.waitFailed(err)
+	}
+	return c.reportStatus(ctx, lc, res, *save)
+}
+
+func (c *cli) waitFailed(err error) int {
+	if errors.Is(err, licenseclient.ErrWaitTimeout) {
+		return c.fail(exitTimeout, errors.New("the waiting time for license approval has expired"))
+	}
+	return c.fail(exitError, err)
+}
+
+func runShowKey(ctx context.Context, c *cli, args []string) int {
+	fs := c.flags()
+	if code, ok := c.parse(fs, args); !ok {
+		return code
+	}
+
+	env, err := c.env()
+	if err != nil {
+		return c.fail(exitError, err)
+	}
+	if env.settings.LicenseKey == "" {
+		return c.fail(exitNotLicensed, errNoLicenseKey)
+	}
+	c.print(struct {
+		LicenseKey string `json:"license_key"`
+	}{env.settings.LicenseKey}, "%s", env.settings.LicenseKey)
+	return exitOK
+}
+
+func runDeactivate(ctx context.Context, c *cli, args []string) int {
+	fs := c.flags()
+	if code, ok := c.parse(fs, args); !ok {
+		return code
+	}
+
+	_, lc, code := c.client(false)
+	if lc == nil {
+		return code
+	}
+	// Освобождаем активацию, например перед заменой машины
+	if err := lc.Deactivate(ctx); err != nil {
+		return c.fail(exitError, err)
+	}
+	c.print(struct {
+		Status     licenseclient.Status `json:"status"`
+		LicenseKey string               `json:"license_key"`
+	}{licenseclient.StatusDeactivated, lc.LicenseKey()}, "License has been deactivated on this machine.")
+	return exitOK
+}
+
+func runVerifyFile(ctx context.Context, c *cli, args []string) int {
+	fs := c.flags()
+	licenseKey := fs.String("key", "", "also require the license to be issued for this license key")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: client verify-file [flags] FILE")
+		fs.PrintDefaults()
+	}
+	if code, ok := c.parse(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	env, err := c.env()
+	if err != nil {
+		return c.fail(exitError, err)
+	}
+	pub, err := licensefile.LoadPublicKey(env.settings.PublicKey)
+	if err != nil {
+		return c.fail(exitError, err)
+	}
+	f, err := licensefile.Load(fs.Arg(0))
+	if err != nil {
+		return c.fail(exitError, err)
+	}
+	lic, err := f.Verify(pub)
+	if err == nil {
+		err = lic.Check(*licenseKey, "", time.Now())
+	}
+	// Продукт из настроек: лицензию другого продукта с тем же ключом подписи не принимаем
+	if err == nil && env.settings.Product != "" {
+		err = lic.CheckProduct(env.settings.Product)
+	}
+	if err != nil {
+		return c.fail(exitInvalidLicense, err)
+	}
+
+	out := struct {
+		Valid bool `json:"valid"`
+		*licensefile.License
+	}{true, lic}
+	c.print(out, "License file is valid.\n  Key:     %s\n  Product: %s %s\n  TAG:     %d\n  Issued:  %s%s",
+		lic.LicenseKey, lic.Product, lic.ProductVersion, lic.Tag, lic.IssuedAt.Format(time.RFC3339), expiresLine(lic))
+	return exitOK
+}
+
+func expiresLine(lic *licensefile.License) string {
+	if lic.ExpiresAt == nil {
+		return ""
+	}
+	return "\n  Expires: " + lic.ExpiresAt.Format(time.RFC3339)
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+
+	"example.com/licence-approval/client/pkg/settings"
+)
+
+// secretKeys — атрибуты журнала, значения которых маскируются
+var secretKeys = map[string]bool{
+	"license_key":   true,
+	"token":         true,
+	"authorization": true,
+}
+
+// configureLogging направляет журнал клиента в stderr: stdout занят результатом команды
+func configureLogging(s *settings.Settings) {
+	opts := &slog.HandlerOptions{Level: s.LogLevel, ReplaceAttr: redactAttr}
+	var h slog.Handler = slog.NewTextHandler(os.Stderr, opts)
+	if s.LogFormat == settings.LogFormatJSON {
+		h = slog.NewJSONHandler(os.Stderr, opts)
+	}
+	slog.SetDefault(slog.New(h))
+}
+
+// redactAttr оставляет от секрета только первые символы
+func redactAttr(_ []string, a slog.Attr) slog.Attr {
+	if !secretKeys[a.Key] || a.Value.Kind() != slog.KindString {
+		return a
+	}
+	v := a.Value.String()
+	if len(v) < 16 {
+		return slog.String(a.Key, "[redacted]")
+	}
+	return slog.String(a.Key, v[:4]+"…[redacted]")
+}
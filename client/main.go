@@ -1,176 +1,192 @@
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"crypto/x509"
-
-	"net/http"
+	"errors"
+	"os/signal"
 	"path/filepath"
-	"sync"
+	"syscall"
 
-	"example.com/licence-approval/client/pkg/config"
-	"example.com/licence-approval/client/pkg/errors"
-	"example.com/licence-approval/client/pkg/handlers"
+	"example.com/licence-approval/client/pkg/cache"
+	"example.com/licence-approval/client/pkg/licenseclient"
+	"example.com/licence-approval/client/pkg/licensefile"
+	"example.com/licence-approval/client/pkg/settings"
 	"example.com/licence-approval/client/pkg/utils"
 
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
-	"time"
 )
 
-const (
-	checkInterval    = 10 * time.Second
-	maxCheckDuration = 5 * time.Minute
+var (
+	errNoLicenseKey = errors.New("license key has not been generated yet; run `client request` first")
+	errNoServerURL  = errors.New("license server URL is not set (server_url / LICENSE_SERVER_URL / --server-url)")
 )
 
+// clientEnv — настройки клиента, общие для всех команд
+type clientEnv struct {
+	settings *settings.Settings
+}
+
 func main() {
-	fmt.Println("=== Client Started ===")
+	name, args := parseCommand(os.Args[1:])
+
+	// Ctrl+C прерывает ожидание одобрения
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	code := runCommand(ctx, name, args)
+	stop()
+	os.Exit(code)
+}
 
-	// Получаем путь к бинарнику
+// loadEnv собирает настройки из флагов, окружения и файлов настроек
+func loadEnv(configFile string, flags map[string]string) (*clientEnv, error) {
+	// Получаем путь к бинарнику: рядом лежат прежний config.json и сертификаты
 	exePath, err := os.Executable()
 	if err != nil {
-		log.Fatalf("Error getting executable path: %v", err)
+		return nil, fmt.Errorf("get executable path: %w", err)
 	}
 	exeDir := filepath.Dir(exePath)
 
-	// Формируем путь к config.json в той же папке
-	configPath := filepath.Join(exeDir, "config.json")
-
-	// Загружаем config.json
-	cfg, err := config.LoadConfig(configPath)
+	s, err := settings.Load(settings.Options{
+		Flags:      flags,
+		ConfigFile: configFile,
+		ExeDir:     exeDir,
+		Defaults: map[string]string{
+			// Сертификат сервера (например, в ../server/config/certs/server.crt)
+			"ca_cert":      filepath.Join(exeDir, "../server/config/certs/server.crt"),
+			"public_key":   filepath.Join(exeDir, "../server/config/keys/public_key.pem"),
+			"grace_period": licenseclient.DefaultGracePeriod.String(),
+		},
+	})
 	if err != nil {
-		log.Fatalf("Failed to load client config: %v", err)
+		return nil, fmt.Errorf("load client settings: %w", err)
 	}
+	return &clientEnv{settings: s}, nil
+}
 
-	// Проверяем LICENSE_SERVER_URL
-	if cfg.LicenseServerURL == "" {
-		log.Fatal("LICENSE_SERVER_URL is not set in config.json")
+// ensureLicenseKey генерирует и сохраняет ключ лицензии, если его ещё нет.
+// Возвращает true, если ключ был создан.
+func (env *clientEnv) ensureLicenseKey() (bool, error) {
+	return env.settings.EnsureLicenseKey(utils.GenerateHexLicenseKey)
+}
 
+// newClient создаёт licenseclient для работы с сервером
+func (env *clientEnv) newClient(extra ...licenseclient.Option) (*licenseclient.Client, error) {
+	s := env.settings
+	if s.ServerURL == "" {
+		return nil, errNoServerURL
 	}
-
-	// Генерируем LicenseKey, если нет
-	if cfg.LicenseKey == "" {
-		licenseKey, err := utils.GenerateHexLicenseKey()
-		if err != nil {
-			log.Fatalf("Failed to generate license key: %v", err)
-		}
-		cfg.LicenseKey = licenseKey
-		fmt.Printf("Generated License Key: %s\n", cfg.LicenseKey)
-
-		// Сохраняем
-		if err := config.SaveConfig(configPath, cfg); err != nil {
-			log.Fatalf("Failed to save config with new license key: %v", err)
-
-		}
-	} else {
-		fmt.Printf("Using existing License Key: %s\n", cfg.LicenseKey)
+	if s.LicenseKey == "" {
+		return nil, errNoLicenseKey
 	}
 
-	// Читаем сертификат сервера (например, в ../server/config/certs/server.crt)
-	certPath := filepath.Join(exeDir, "../server/config/certs/server.crt")
-
-	caCert, err := os.ReadFile(certPath)
+	tlsConfig, err := env.tlsConfig()
 	if err != nil {
-		log.Fatalf("Failed to read server certificate: %v", err)
+		return nil, err
 	}
 
-	// Создаём пул доверенных сертификатов
-	caCertPool := x509.NewCertPool()
-	if !caCertPool.AppendCertsFromPEM(caCert) {
-		log.Fatalf("Failed to append server certificate to CA pool")
+	// Кэш последнего вердикта сервера для работы в offline grace period
+	if err := os.MkdirAll(s.StateDir, 0o700); err != nil {
+		return nil, fmt.Errorf("create state directory: %w", err)
 	}
+	licenseCache := cache.NewStore(s.Path("license-cache.json"))
+
+	// После ошибок связи паузы растут до backoff_max
+	backoff := licenseclient.DefaultBackoff
+	backoff.Max = s.BackoffMax
+
+	opts := append(env.baseOptions(),
+		licenseclient.WithTLSConfig(tlsConfig),
+		licenseclient.WithPinnedKeys(s.PinSHA256...),
+		licenseclient.WithProxy(s.Proxy),
+		licenseclient.WithCache(licenseCache, s.GracePeriod),
+		licenseclient.WithPollInterval(s.PollInterval),
+		licenseclient.WithMaxWait(s.MaxWait),
+		licenseclient.WithBackoff(backoff),
+		licenseclient.OnError(func(err error) {
+			slog.Warn("Failed to check license", "error", err)
+		}),
+		licenseclient.OnStatusChange(func(prev licenseclient.Status, res *licenseclient.Result) {
+			if prev != "" {
+				slog.Info("License status changed", "from", prev, "to", res.Status)
+			}
+		}),
+	)
+	opts = append(opts, env.certificateOptions()...)
+	return licenseclient.New(s.ServerURL, s.LicenseKey, append(opts, extra...)...)
+}
 
-	// Настраиваем TLS
-	tlsConfig := &tls.Config{RootCAs: caCertPool}
-
-	// Создаём HTTP-клиент
-	httpClient := &http.Client{
-		Timeout:   10 * time.Second,
-		Transport: &http.Transport{TLSClientConfig: tlsConfig},
+// newOfflineClient создаёт licenseclient для команд офлайн-активации: сеть и
+// сертификат сервера им не нужны
+func (env *clientEnv) newOfflineClient() (*licenseclient.Client, error) {
+	if env.settings.LicenseKey == "" {
+		return nil, errNoLicenseKey
 	}
+	return licenseclient.New(env.settings.ServerURL, env.settings.LicenseKey, env.baseOptions()...)
+}
 
-	var wg sync.WaitGroup
-	wg.Add(1)
-
-	// Запуск проверки лицензии
-	go func() {
-		defer wg.Done()
-
-		hasLicense, message, err := handlers.CheckLicense(httpClient, cfg.LicenseServerURL, cfg.LicenseKey)
-
-		if err != nil {
-			log.Printf("Failed to check license: %v", err)
-			return
-		}
+// baseOptions — настройки продукта и файл офлайн-активации, если известен ключ продукта
+func (env *clientEnv) baseOptions() []licenseclient.Option {
+	opts := settingsOptions(env.settings)
+	if pub, err := licensefile.LoadPublicKey(env.settings.PublicKey); err == nil {
+		opts = append(opts, licenseclient.WithLicenseFile(env.licenseFilePath(), pub))
+	}
+	return opts
+}
 
-		if hasLicense {
-			fmt.Println("License is active. The client can proceed.")
-			return
-		} else {
-			switch message {
-			case "License is active.":
-				fmt.Println("License is active. The client can proceed.")
-				return
-			case "License request is pending.":
-				log.Println("License request is pending. Waiting for approval...")
-			case "License request has been rejected.":
-				log.Println("Your license request has been rejected by the administrator. Please contact support.")
-				return
-			default:
-				log.Println("License is not active. Creating a new license request...")
-			}
+// tlsConfig — доверенные CA сервера. Бандл ca_cert заменяет системные корневые
+// сертификаты, с system_roots — дополняет их. Без бандла используются системные;
+// прежний путь по умолчанию рядом с бинарником необязателен.
+func (env *clientEnv) tlsConfig() (*tls.Config, error) {
+	s := env.settings
+	var pool *x509.CertPool
+	if s.SystemRoots {
+		var err error
+		if pool, err = x509.SystemCertPool(); err != nil {
+			return nil, fmt.Errorf("load system root certificates: %w", err)
 		}
+	}
 
-		// Создаём заявку
-		requestID, err := handlers.CreateLicenseRequest(httpClient, cfg.LicenseServerURL, cfg.LicenseKey)
-
-		if err != nil {
-			if reqErr, ok := err.(*errors.LicenseRequestExistsError); ok {
-				log.Printf("License request already exists with ID %d. Waiting for approval...", reqErr.RequestID)
-			} else {
-				log.Printf("Failed to create license request: %v", err)
-				return
+	if s.CACert != "" {
+		bundle, err := os.ReadFile(s.CACert)
+		switch {
+		case err == nil:
+			if pool == nil {
+				pool = x509.NewCertPool()
 			}
-		} else {
-			log.Printf("License request #%d created. Waiting for approval...", requestID)
-		}
-
-		ticker := time.NewTicker(checkInterval)
-		defer ticker.Stop()
-		timeoutChan := time.After(maxCheckDuration)
-
-		for {
-			select {
-			case <-ticker.C:
-				hasLicenseNow, msg, err := handlers.CheckLicense(httpClient, cfg.LicenseServerURL, cfg.LicenseKey)
-
-				if err != nil {
-					if _, ok := err.(*errors.LicenseRejectedError); ok {
-						fmt.Println("Your license request has been rejected by the administrator.")
-						return
-					}
-					log.Printf("Failed to check license: %v", err)
-					continue
-				}
-				if hasLicenseNow {
-					fmt.Println("License approved! The client can proceed.")
-					return
-				} else {
-					log.Printf("License status: %s. Continuing to check...", msg)
-				}
-
-			case <-timeoutChan:
-				fmt.Println("The waiting time for license approval has expired.")
-				os.Exit(1)
+			if !pool.AppendCertsFromPEM(bundle) {
+				return nil, fmt.Errorf("no certificates in CA bundle %s", s.CACert)
 			}
+		case errors.Is(err, os.ErrNotExist) && s.Sources["ca_cert"] == "default":
+		default:
+			return nil, fmt.Errorf("read CA bundle: %w", err)
 		}
-	}()
+	}
+	// pool == nil — системные корневые сертификаты
+	return &tls.Config{RootCAs: pool}, nil
+}
 
-	fmt.Println("=== Client is running ===")
+// certificateOptions — сертификат клиента для взаимного TLS: заданный в настройках
+// либо выданный сервером при одобрении заявки (mtls)
+func (env *clientEnv) certificateOptions() []licenseclient.Option {
+	s := env.settings
+	switch {
+	case s.ClientCert != "":
+		return []licenseclient.Option{licenseclient.WithClientCertificate(s.ClientCert, s.ClientKey)}
+	case s.MTLS:
+		return []licenseclient.Option{licenseclient.WithIssuedCertificate(s.Path("client.crt"), s.Path("client.key"))}
+	}
+	return nil
+}
 
-	// Ждём завершения проверки
-	wg.Wait()
+// licenseFilePath — лицензия, импортированная офлайн-активацией
+func (env *clientEnv) licenseFilePath() string {
+	return env.settings.Path("license.json")
+}
 
-	fmt.Println("=== Client Finished ===")
+// installationKeyPath — ключ установки, которым подписываются файлы заявок
+func (env *clientEnv) installationKeyPath() string {
+	return env.settings.Path("installation.key")
 }
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"example.com/licence-approval/client/pkg/activation"
+	"example.com/licence-approval/client/pkg/licensefile"
+)
+
+// runExportRequest выгружает подписанный файл заявки для офлайн-активации
+func runExportRequest(ctx context.Context, c *cli, args []string) int {
+	fs := c.flags()
+	out := fs.String("out", "activation-request.json", "write the activation request to `file`")
+	if code, ok := c.parse(fs, args); !ok {
+		return code
+	}
+
+	env, err := c.env()
+	if err != nil {
+		return c.fail(exitError, err)
+	}
+	if _, err := env.ensureLicenseKey(); err != nil {
+		return c.fail(exitError, err)
+	}
+	lc, err := env.newOfflineClient()
+	if err != nil {
+		return c.fail(exitError, err)
+	}
+	key, err := activation.LoadOrCreateKey(env.installationKeyPath())
+	if err != nil {
+		return c.fail(exitError, fmt.Errorf("installation key: %w", err))
+	}
+
+	req := lc.ActivationRequest()
+	f, err := activation.NewRequestFile(req, key)
+	if err != nil {
+		return c.fail(exitError, err)
+	}
+	if err := f.Save(*out); err != nil {
+		return c.fail(exitError, fmt.Errorf("save activation request: %w", err))
+	}
+
+	c.print(struct {
+		File        string `json:"file"`
+		LicenseKey  string `json:"license_key"`
+		Fingerprint string `json:"fingerprint"`
+	}{*out, req.LicenseKey, req.Fingerprint},
+		"Activation request saved to %s.\nPass it to the license administrator and import the response with \"client import-response FILE\".", *out)
+	return exitOK
+}
+
+// runImportResponse проверяет файл ответа администратора и активирует лицензию без сети
+func runImportResponse(ctx context.Context, c *cli, args []string) int {
+	fs := c.flags()
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: client import-response [flags] FILE")
+		fs.PrintDefaults()
+	}
+	if code, ok := c.parse(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	env, err := c.env()
+	if err != nil {
+		return c.fail(exitError, err)
+	}
+	lc, err := env.newOfflineClient()
+	if err != nil {
+		return c.fail(exitNotLicensed, err)
+	}
+	resp, err := activation.LoadResponse(fs.Arg(0))
+	if err != nil {
+		return c.fail(exitError, err)
+	}
+	lic, err := lc.ImportActivation(resp)
+	if err != nil {
+		if _, statErr := os.Stat(env.settings.PublicKey); statErr != nil {
+			err = fmt.Errorf("%w (product public key %s: %v)", err, env.settings.PublicKey, statErr)
+		}
+		return c.fail(exitInvalidLicense, err)
+	}
+
+	out := struct {
+		Status      string `json:"status"`
+		RequestID   int    `json:"request_id"`
+		LicenseFile string `json:"license_file"`
+		*licensefile.License
+	}{"active", resp.RequestID, env.licenseFilePath(), lic}
+	c.print(out, "License activated offline (request #%d).\n  Product: %s %s\n  TAG:     %d%s\nLicense saved to %s",
+		resp.RequestID, lic.Product, lic.ProductVersion, lic.Tag, expiresLine(lic), env.licenseFilePath())
+	return exitOK
+}
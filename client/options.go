@@ -0,0 +1,26 @@
+package main
+
+import (
+	"example.com/licence-approval/client/pkg/licenseclient"
+	"example.com/licence-approval/client/pkg/settings"
+)
+
+// settingsOptions переводит настройки клиента в опции licenseclient.
+// Без продукта сервер использует продукт по умолчанию, а без организации
+// её назначает администратор.
+func settingsOptions(s *settings.Settings) []licenseclient.Option {
+	opts := []licenseclient.Option{
+		licenseclient.WithProduct(s.Product, s.ProductVersion),
+		licenseclient.WithOrganization(s.Organization),
+		licenseclient.WithInviteCode(s.InviteCode),
+		licenseclient.WithRequesterEmail(s.RequesterEmail),
+		licenseclient.WithRequesterName(s.RequesterName),
+		licenseclient.WithJustification(s.Justification),
+		licenseclient.WithAppVersion(s.AppVersion),
+		licenseclient.WithMetadata(s.Metadata),
+	}
+	if s.Trial {
+		opts = append(opts, licenseclient.WithTrial())
+	}
+	return opts
+}
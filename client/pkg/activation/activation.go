@@ -0,0 +1,153 @@
+// Package activation — офлайн-активация для машин без доступа к серверу лицензий.
+//
+// Клиент выгружает файл заявки, подписанный ключом установки (Ed25519),
+// администратор загружает его в админке и получает файл ответа с лицензией,
+// подписанной ключом продукта. Клиент импортирует ответ и проверяет его сам.
+package activation
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"example.com/licence-approval/client/pkg/licensefile"
+)
+
+// Форматы файлов; совпадают с форматами сервера
+const (
+	RequestFormat  = "licence-approval/activation-request/v1"
+	ResponseFormat = "licence-approval/activation-response/v1"
+)
+
+// Request — содержимое заявки на офлайн-активацию
+type Request struct {
+	LicenseKey     string    `json:"license_key"`
+	Fingerprint    string    `json:"fingerprint"`
+	Hostname       string    `json:"hostname"`
+	Product        string    `json:"product"`
+	ProductVersion string    `json:"product_version"`
+	Organization   string    `json:"organization"`
+	InviteCode     string    `json:"invite_code"`
+	RequesterEmail string    `json:"requester_email"`
+	CreatedAt      time.Time `json:"created_at"`
+
+	RequesterName string            `json:"requester_name,omitempty"`
+	Justification string            `json:"justification,omitempty"`
+	OS            string            `json:"os,omitempty"`
+	Arch          string            `json:"arch,omitempty"`
+	AppVersion    string            `json:"app_version,omitempty"`
+	Metadata      map[string]string `json:"metadata,omitempty"`
+}
+
+// RequestFile — файл заявки: Request в виде подписанной строки JSON
+type RequestFile struct {
+	Format    string `json:"format"`
+	Request   string `json:"request"`
+	PublicKey string `json:"public_key"`
+	Signature string `json:"signature"`
+}
+
+// ResponseFile — файл ответа администратора
+type ResponseFile struct {
+	Format    string `json:"format"`
+	RequestID int    `json:"request_id"`
+	License   string `json:"license"`
+	Signature string `json:"signature"`
+}
+
+// NewRequestFile подписывает заявку ключом установки
+func NewRequestFile(req *Request, key ed25519.PrivateKey) (*RequestFile, error) {
+	if req.LicenseKey == "" || req.Fingerprint == "" {
+		return nil, errors.New("activation request needs a license key and a machine fingerprint")
+	}
+	if req.CreatedAt.IsZero() {
+		req.CreatedAt = time.Now().UTC()
+	}
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	return &RequestFile{
+		Format:    RequestFormat,
+		Request:   string(payload),
+		PublicKey: base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey)),
+		Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(key, payload)),
+	}, nil
+}
+
+// Save записывает файл заявки
+func (f *RequestFile) Save(path string) error {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// LoadResponse читает файл ответа
+func LoadResponse(path string) (*ResponseFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f ResponseFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("parse activation response %s: %w", path, err)
+	}
+	if f.Format != ResponseFormat {
+		return nil, fmt.Errorf("unsupported activation response format %q", f.Format)
+	}
+	return &f, nil
+}
+
+// LicenseFile — подписанная лицензия из ответа
+func (f *ResponseFile) LicenseFile() *licensefile.File {
+	return &licensefile.File{License: f.License, Signature: f.Signature}
+}
+
+// LoadOrCreateKey читает ключ установки из PEM (PKCS#8) или создаёт новый с правами 0600
+func LoadOrCreateKey(path string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return createKey(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return nil, fmt.Errorf("no private key in %s", path)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse key %s: %w", path, err)
+	}
+	key, ok := parsed.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("key %s is not an Ed25519 key", path)
+	}
+	return key, nil
+}
+
+func createKey(path string) (ed25519.PrivateKey, error) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return nil, fmt.Errorf("save key %s: %w", path, err)
+	}
+	return key, nil
+}
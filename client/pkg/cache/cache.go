@@ -0,0 +1,190 @@
+// Package cache хранит последнюю лицензию, подписанную сервером, чтобы клиент
+// мог работать ограниченное время (grace period), пока сервер недоступен.
+//
+// Достоверность лицензии подтверждает подпись сервера: без закрытого ключа
+// продукта лицензию в кэше не подделать и не перенести на другой ключ или машину.
+// Время последней проверки (checked_at) сервер вписывает в подписанную лицензию,
+// поэтому grace period отсчитывается от него, а не от отметок в файле кэша.
+package cache
+
+import (
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"example.com/licence-approval/client/pkg/licensefile"
+)
+
+// Допустимое расхождение часов, прежде чем считать, что их перевели назад:
+// с собственной отметкой клиента и с временем проверки на сервере (его часы могут отличаться)
+const (
+	clockSkewTolerance  = 5 * time.Minute
+	serverSkewTolerance = time.Hour
+)
+
+var (
+	ErrCorrupted     = errors.New("license cache is corrupted")
+	ErrNoLicense     = errors.New("cached license is not active")
+	ErrNoPublicKey   = errors.New("product public key is not configured")
+	ErrGraceExpired  = errors.New("offline grace period has expired")
+	ErrClockRollback = errors.New("system clock was moved back")
+	ErrKeyMismatch   = errors.New("cached license belongs to another license key")
+	ErrNoCheckTime   = errors.New("cached license has no signed check time")
+)
+
+// State — последний вердикт сервера
+type State struct {
+	LicenseKey string `json:"license_key"`
+	// Подписанная лицензия из последнего ответа; nil — лицензия не действовала
+	License *licensefile.File `json:"license,omitempty"`
+	// Самое позднее локальное время, которое видел клиент; растёт при каждом запуске
+	LastSeen time.Time `json:"last_seen"`
+}
+
+// Store — файл кэша
+type Store struct {
+	path string
+}
+
+// NewStore открывает кэш по пути path
+func NewStore(path string) *Store {
+	return &Store{path: path}
+}
+
+// Load читает кэш. Если файла нет, возвращает ошибку os.ErrNotExist.
+func (s *Store) Load() (*State, error) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, err
+	}
+	var st State
+	if err := json.Unmarshal(data, &st); err != nil {
+		return nil, ErrCorrupted
+	}
+	return &st, nil
+}
+
+// Save атомарно записывает кэш (временный файл + rename)
+func (s *Store) Save(st *State) error {
+	data, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".license-cache-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0o600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// Record сохраняет свежий вердикт сервера: license — подписанная лицензия из
+// ответа, nil — лицензия не действует (работа без сервера после этого запрещена)
+func (s *Store) Record(licenseKey string, license *licensefile.File, now time.Time) error {
+	st := &State{
+		LicenseKey: licenseKey,
+		License:    license,
+		LastSeen:   now,
+	}
+	// Отметку LastSeen никогда не уменьшаем
+	if prev, err := s.Load(); err == nil && prev.LastSeen.After(now) {
+		st.LastSeen = prev.LastSeen
+	}
+	return s.Save(st)
+}
+
+// Offline решает, можно ли работать без сервера: лицензия в кэше подписана
+// ключом продукта pub, выдана на licenseKey и не истекла, а grace period от
+// подписанного времени проверки не закончился. Продвигает отметку LastSeen. Возвращает лицензию и момент, до
+// которого можно работать без сервера.
+func (s *Store) Offline(licenseKey string, pub *rsa.PublicKey, grace time.Duration, now time.Time) (*licensefile.License, time.Time, error) {
+	st, err := s.Load()
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	if st.LicenseKey != licenseKey {
+		return nil, time.Time{}, ErrKeyMismatch
+	}
+	if st.License == nil {
+		return nil, time.Time{}, ErrNoLicense
+	}
+	if pub == nil {
+		return nil, time.Time{}, ErrNoPublicKey
+	}
+	lic, err := st.License.Verify(pub)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	if lic.CheckedAt == nil {
+		return nil, time.Time{}, ErrNoCheckTime
+	}
+	// Часы отстают от уже виденного времени — вероятно, их перевели назад
+	if now.Add(clockSkewTolerance).Before(st.LastSeen) || now.Add(serverSkewTolerance).Before(*lic.CheckedAt) {
+		return nil, time.Time{}, ErrClockRollback
+	}
+	if err := lic.Check(licenseKey, "", now); err != nil {
+		return nil, time.Time{}, fmt.Errorf("cached license: %w", err)
+	}
+
+	until := lic.CheckedAt.Add(grace)
+	if now.After(until) {
+		return nil, until, ErrGraceExpired
+	}
+	// Пробная лицензия не продлевается grace period
+	if lic.ExpiresAt != nil && lic.ExpiresAt.Before(until) {
+		until = *lic.ExpiresAt
+	}
+
+	if now.After(st.LastSeen) {
+		st.LastSeen = now
+		if err := s.Save(st); err != nil {
+			return nil, until, err
+		}
+	}
+	return lic, until, nil
+}
+
+// DateTracker — http.RoundTripper, запоминающий время сервера из заголовка Date
+type DateTracker struct {
+	Base http.RoundTripper
+
+	mu   sync.Mutex
+	last time.Time
+}
+
+func (t *DateTracker) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.Base.RoundTrip(req)
+	if err == nil {
+		if date, perr := http.ParseTime(resp.Header.Get("Date")); perr == nil {
+			t.mu.Lock()
+			t.last = date
+			t.mu.Unlock()
+		}
+	}
+	return resp, err
+}
+
+// Last возвращает время сервера из последнего ответа (нулевое, если ответов не было)
+func (t *DateTracker) Last() time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.last
+}
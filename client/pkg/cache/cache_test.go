@@ -0,0 +1,278 @@
+package cache
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"example.com/licence-approval/client/pkg/licensefile"
+)
+
+const testKey = "LIC-0001"
+
+var (
+	productKey = mustKey()
+	otherKey   = mustKey()
+	checkedAt  = time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+)
+
+func mustKey() *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+	return key
+}
+
+// signed подписывает лицензию так же, как сервер
+func signed(t *testing.T, key *rsa.PrivateKey, lic licensefile.License) *licensefile.File {
+	t.Helper()
+	data, err := json.Marshal(lic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash := sha256.Sum256(data)
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hash[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &licensefile.File{License: string(data), Signature: base64.StdEncoding.EncodeToString(sig)}
+}
+
+func newStore(t *testing.T) *Store {
+	t.Helper()
+	return NewStore(filepath.Join(t.TempDir(), "license-cache.json"))
+}
+
+func TestOffline(t *testing.T) {
+	const grace = 72 * time.Hour
+	trialEnd := checkedAt.Add(24 * time.Hour)
+	expired := checkedAt.Add(-time.Hour)
+
+	full := licensefile.License{LicenseKey: testKey, Product: "editor", Tag: 3, IssuedAt: checkedAt, CheckedAt: &checkedAt}
+	trial := full
+	trial.Trial, trial.ExpiresAt = true, &trialEnd
+	expiredTrial := full
+	expiredTrial.Trial, expiredTrial.ExpiresAt = true, &expired
+	otherLicense := full
+	otherLicense.LicenseKey = "LIC-0002"
+	unchecked := full
+	unchecked.CheckedAt = nil
+
+	forged := signed(t, productKey, full)
+	forged.License = `{"license_key":"LIC-0001","product":"editor","tag":1000}`
+	// Время проверки сдвинуто вперёд, чтобы продлить grace period, подпись прежняя
+	extended := signed(t, productKey, full)
+	later := checkedAt.Add(30 * 24 * time.Hour)
+	moved := full
+	moved.CheckedAt = &later
+	if data, err := json.Marshal(moved); err == nil {
+		extended.License = string(data)
+	}
+
+	tests := []struct {
+		name      string
+		license   *licensefile.File
+		cachedKey string
+		noPubKey  bool
+		now       time.Time
+		wantUntil time.Time
+		wantErr   error
+	}{
+		{
+			name:      "within grace period",
+			license:   signed(t, productKey, full),
+			now:       checkedAt.Add(time.Hour),
+			wantUntil: checkedAt.Add(grace),
+		},
+		{
+			name:    "grace period expired",
+			license: signed(t, productKey, full),
+			now:     checkedAt.Add(grace + time.Minute),
+			wantErr: ErrGraceExpired,
+		},
+		{
+			name:      "trial ends before grace period",
+			license:   signed(t, productKey, trial),
+			now:       checkedAt.Add(time.Hour),
+			wantUntil: trialEnd,
+		},
+		{
+			name:    "trial expired while offline",
+			license: signed(t, productKey, trial),
+			now:     trialEnd.Add(time.Minute),
+			wantErr: licensefile.ErrExpired,
+		},
+		{
+			name:    "trial expired before the check",
+			license: signed(t, productKey, expiredTrial),
+			now:     checkedAt.Add(time.Minute),
+			wantErr: licensefile.ErrExpired,
+		},
+		{
+			name:    "edited license",
+			license: forged,
+			now:     checkedAt.Add(time.Hour),
+			wantErr: licensefile.ErrBadSignature,
+		},
+		{
+			name:    "edited check time",
+			license: extended,
+			now:     checkedAt.Add(grace + time.Minute),
+			wantErr: licensefile.ErrBadSignature,
+		},
+		{
+			name:    "no signed check time",
+			license: signed(t, productKey, unchecked),
+			now:     checkedAt.Add(time.Hour),
+			wantErr: ErrNoCheckTime,
+		},
+		{
+			name:    "signed with another key",
+			license: signed(t, otherKey, full),
+			now:     checkedAt.Add(time.Hour),
+			wantErr: licensefile.ErrBadSignature,
+		},
+		{
+			name:    "license of another key",
+			license: signed(t, productKey, otherLicense),
+			now:     checkedAt.Add(time.Hour),
+			wantErr: licensefile.ErrKeyMismatch,
+		},
+		{
+			name:      "cache of another key",
+			license:   signed(t, productKey, full),
+			cachedKey: "LIC-0002",
+			now:       checkedAt.Add(time.Hour),
+			wantErr:   ErrKeyMismatch,
+		},
+		{
+			name:    "last verdict was not active",
+			now:     checkedAt.Add(time.Hour),
+			wantErr: ErrNoLicense,
+		},
+		{
+			name:     "no product key",
+			license:  signed(t, productKey, full),
+			noPubKey: true,
+			now:      checkedAt.Add(time.Hour),
+			wantErr:  ErrNoPublicKey,
+		},
+		{
+			name:    "clock moved back",
+			license: signed(t, productKey, full),
+			now:     checkedAt.Add(-2 * time.Hour),
+			wantErr: ErrClockRollback,
+		},
+		{
+			name:      "small clock skew is tolerated",
+			license:   signed(t, productKey, full),
+			now:       checkedAt.Add(-time.Minute),
+			wantUntil: checkedAt.Add(grace),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newStore(t)
+			key := testKey
+			if tt.cachedKey != "" {
+				key = tt.cachedKey
+			}
+			if err := s.Record(key, tt.license, checkedAt); err != nil {
+				t.Fatal(err)
+			}
+			pub := &productKey.PublicKey
+			if tt.noPubKey {
+				pub = nil
+			}
+
+			lic, until, err := s.Offline(testKey, pub, grace, tt.now)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Offline() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if lic == nil || lic.LicenseKey != testKey {
+				t.Errorf("Offline() license = %+v", lic)
+			}
+			if !until.Equal(tt.wantUntil) {
+				t.Errorf("Offline() until = %s, want %s", until, tt.wantUntil)
+			}
+		})
+	}
+}
+
+func TestOfflineIgnoresLocalCheckTime(t *testing.T) {
+	s := newStore(t)
+	f := signed(t, productKey, licensefile.License{LicenseKey: testKey, CheckedAt: &checkedAt})
+	// Запись кэша позже подписанного времени проверки не продлевает grace period
+	if err := s.Record(testKey, f, checkedAt.Add(48*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	_, until, err := s.Offline(testKey, &productKey.PublicKey, 72*time.Hour, checkedAt.Add(80*time.Hour))
+	if !errors.Is(err, ErrGraceExpired) {
+		t.Fatalf("Offline() error = %v, want %v", err, ErrGraceExpired)
+	}
+	if want := checkedAt.Add(72 * time.Hour); !until.Equal(want) {
+		t.Errorf("Offline() until = %s, want %s", until, want)
+	}
+}
+
+func TestLastSeenNeverDecreases(t *testing.T) {
+	s := newStore(t)
+	f := signed(t, productKey, licensefile.License{LicenseKey: testKey, CheckedAt: &checkedAt})
+	if err := s.Record(testKey, f, checkedAt); err != nil {
+		t.Fatal(err)
+	}
+
+	later := checkedAt.Add(10 * time.Hour)
+	if _, _, err := s.Offline(testKey, &productKey.PublicKey, 72*time.Hour, later); err != nil {
+		t.Fatal(err)
+	}
+	st, err := s.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !st.LastSeen.Equal(later) {
+		t.Errorf("LastSeen = %s, want %s", st.LastSeen, later)
+	}
+
+	// Новый вердикт с отстающими часами не сбрасывает отметку
+	if err := s.Record(testKey, f, checkedAt.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if st, err = s.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if !st.LastSeen.Equal(later) {
+		t.Errorf("LastSeen after Record = %s, want %s", st.LastSeen, later)
+	}
+
+	// ...и работа без сервера после этого требует часов не раньше отметки
+	_, _, err = s.Offline(testKey, &productKey.PublicKey, 72*time.Hour, checkedAt.Add(2*time.Hour))
+	if !errors.Is(err, ErrClockRollback) {
+		t.Errorf("Offline() error = %v, want %v", err, ErrClockRollback)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	s := newStore(t)
+	if _, err := s.Load(); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Load() of missing file error = %v, want os.ErrNotExist", err)
+	}
+	if err := os.WriteFile(s.path, []byte("{not json"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Load(); !errors.Is(err, ErrCorrupted) {
+		t.Errorf("Load() of corrupted file error = %v, want %v", err, ErrCorrupted)
+	}
+}
@@ -0,0 +1,96 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Коды ошибок сервера (поле code ответа application/problem+json)
+const (
+	CodeBadRequest          = "bad_request"
+	CodeLicenseKeyRequired  = "license_key_required"
+	CodeUnknownProduct      = "unknown_product"
+	CodeUnknownVersion      = "unknown_version"
+	CodeUnknownInviteCode   = "unknown_invite_code"
+	CodeRequestExists       = "request_exists"
+	CodeFingerprintRequired = "fingerprint_required"
+	CodeTrialUsed           = "trial_used"
+	CodeNotActive           = "not_active"
+	CodeFingerprintMismatch = "fingerprint_mismatch"
+	CodeInvalidCSR          = "invalid_csr"
+	CodeClientCertRequired  = "client_certificate_required"
+	CodeClientCertMismatch  = "client_certificate_mismatch"
+	CodeInternal            = "internal_error"
+)
+
+// Problem — ошибка сервера в формате RFC 7807
+type Problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail"`
+	Instance  string `json:"instance"`
+	Code      string `json:"code"`
+	RequestID int    `json:"request_id"`
+}
+
+func (p *Problem) Error() string {
+	msg := p.Title
+	if p.Detail != "" && p.Detail != p.Title {
+		if msg != "" {
+			msg += ": "
+		}
+		msg += p.Detail
+	}
+	if p.Code != "" {
+		return fmt.Sprintf("%s (%s, HTTP %d)", msg, p.Code, p.Status)
+	}
+	return fmt.Sprintf("%s (HTTP %d)", msg, p.Status)
+}
+
+// ProblemDetails возвращает исходный ответ сервера; доступен и у типизированных ошибок
+func (p *Problem) ProblemDetails() *Problem { return p }
+
+// ProblemOf возвращает ответ сервера из цепочки ошибок err (nil — ошибка не от сервера)
+func ProblemOf(err error) *Problem {
+	var pd interface{ ProblemDetails() *Problem }
+	if errors.As(err, &pd) {
+		return pd.ProblemDetails()
+	}
+	return nil
+}
+
+// UnknownProductError — сервер не знает продукт или его версию
+type UnknownProductError struct{ *Problem }
+
+// LicenseNotActiveError — у ключа нет действующей активации
+type LicenseNotActiveError struct{ *Problem }
+
+// FingerprintMismatchError — лицензия активирована на другой машине
+type FingerprintMismatchError struct{ *Problem }
+
+// TrialUsedError — пробная лицензия на этой машине уже использована
+type TrialUsedError struct{ *Problem }
+
+// ClientCertificateError — сервер требует клиентский сертификат или он выдан другому ключу
+type ClientCertificateError struct{ *Problem }
+
+// FromProblem переводит ошибку сервера в типизированную ошибку пакета.
+// Коды без отдельного типа возвращаются как *Problem.
+func FromProblem(p *Problem) error {
+	switch p.Code {
+	case CodeRequestExists:
+		return &LicenseRequestExistsError{RequestID: p.RequestID}
+	case CodeUnknownProduct, CodeUnknownVersion:
+		return &UnknownProductError{p}
+	case CodeNotActive:
+		return &LicenseNotActiveError{p}
+	case CodeFingerprintMismatch:
+		return &FingerprintMismatchError{p}
+	case CodeTrialUsed:
+		return &TrialUsedError{p}
+	case CodeClientCertRequired, CodeClientCertMismatch:
+		return &ClientCertificateError{p}
+	}
+	return p
+}
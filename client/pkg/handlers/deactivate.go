@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// DeactivateLicense освобождает активацию лицензии на этой машине,
+// чтобы её можно было перенести на другую.
+func DeactivateLicense(httpClient *http.Client, serverURL, licenseKey string) error {
+	body, err := json.Marshal(map[string]string{"license_key": licenseKey})
+	if err != nil {
+		return err
+	}
+
+	resp, err := httpClient.Post(strings.TrimRight(serverURL, "/")+"/api/deactivate",
+		"application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("deactivate request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return responseError(resp)
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"example.com/licence-approval/client/pkg/licensefile"
+)
+
+var (
+	ErrNoLicenseData       = errors.New("server did not return a signed license")
+	ErrNoClientCertificate = errors.New("server did not return a client certificate")
+)
+
+// FetchLicense получает подписанную лицензию для активного ключа
+func FetchLicense(httpClient *http.Client, serverURL, licenseKey string) (*licensefile.File, error) {
+	body, err := CheckStatus(httpClient, serverURL, licenseKey)
+	if err != nil {
+		return nil, fmt.Errorf("fetch license failed: %w", err)
+	}
+	if !body.HasLicense || body.License == "" || body.Signature == "" {
+		return nil, ErrNoLicenseData
+	}
+	return &licensefile.File{License: body.License, Signature: body.Signature}, nil
+}
+
+// FetchClientCertificate получает клиентский сертификат (PEM), выданный сервером
+// активному ключу для взаимного TLS
+func FetchClientCertificate(httpClient *http.Client, serverURL, licenseKey string) ([]byte, error) {
+	body, err := CheckStatus(httpClient, serverURL, licenseKey)
+	if err != nil {
+		return nil, fmt.Errorf("fetch client certificate failed: %w", err)
+	}
+	if !body.HasLicense || body.ClientCertificate == "" {
+		return nil, ErrNoClientCertificate
+	}
+	return []byte(body.ClientCertificate), nil
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	licerrors "example.com/licence-approval/client/pkg/errors"
+)
+
+// responseError переводит ответ сервера с ошибкой в ошибку пакета errors:
+// application/problem+json — в типизированную ошибку по коду, иначе — текст ответа
+func responseError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType == "application/problem+json" {
+		var p licerrors.Problem
+		if err := json.Unmarshal(body, &p); err == nil {
+			if p.Status == 0 {
+				p.Status = resp.StatusCode
+			}
+			return licerrors.FromProblem(&p)
+		}
+	}
+
+	// Прежние версии сервера отвечали на повторную заявку JSON с request_id
+	if resp.StatusCode == http.StatusConflict {
+		var existing struct {
+			RequestID int `json:"request_id"`
+		}
+		if json.Unmarshal(body, &existing) == nil && existing.RequestID != 0 {
+			return &licerrors.LicenseRequestExistsError{RequestID: existing.RequestID}
+		}
+	}
+	msg := strings.TrimSpace(string(body))
+	if len(msg) > 1024 {
+		msg = msg[:1024]
+	}
+	return fmt.Errorf("server returned %s: %s", resp.Status, msg)
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Статусы лицензии в ответах сервера (поле status)
+const (
+	StatusActive      = "active"
+	StatusPending     = "pending"
+	StatusRejected    = "rejected"
+	StatusNotActive   = "not_active"
+	StatusExpired     = "expired"
+	StatusDeactivated = "deactivated"
+	StatusTransferred = "transferred"
+)
+
+// StatusResponse — ответ /api/check-license. Status пуст у серверов, которые
+// сообщают статус только текстом Message.
+type StatusResponse struct {
+	Status     string     `json:"status"`
+	Reason     string     `json:"reason"`
+	RequestID  int        `json:"request_id"`
+	HasLicense bool       `json:"has_license"`
+	Message    string     `json:"message"`
+	Product    string     `json:"product"`
+	Trial      bool       `json:"trial"`
+	CreatedAt  *time.Time `json:"created_at"`
+	DecidedAt  *time.Time `json:"decided_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	// RetryAfter — через сколько секунд проверить снова
+	RetryAfter        int    `json:"retry_after"`
+	License           string `json:"license"`
+	Signature         string `json:"signature"`
+	ClientCertificate string `json:"client_certificate"`
+}
+
+// CreateResponse — ответ /api/create-license-request
+type CreateResponse struct {
+	RequestID int        `json:"request_id"`
+	Status    string     `json:"status"`
+	Reason    string     `json:"reason"`
+	Message   string     `json:"message"`
+	Trial     bool       `json:"trial"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// CheckStatus запрашивает статус лицензии. Ошибки сервера возвращаются
+// типизированными ошибками пакета errors.
+func CheckStatus(httpClient *http.Client, serverURL, licenseKey string) (*StatusResponse, error) {
+	endpoint := strings.TrimRight(serverURL, "/") + "/api/check-license?license_key=" + url.QueryEscape(licenseKey)
+	resp, err := httpClient.Get(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError(resp)
+	}
+	var body StatusResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	return &body, nil
+}
+
+// RequestDetails — сведения для администратора, который решает по заявке
+type RequestDetails struct {
+	RequesterName string            `json:"requester_name,omitempty"`
+	Justification string            `json:"justification,omitempty"`
+	OS            string            `json:"os,omitempty"`
+	Arch          string            `json:"arch,omitempty"`
+	AppVersion    string            `json:"app_version,omitempty"`
+	Metadata      map[string]string `json:"metadata,omitempty"`
+}
+
+// RequestLicense создаёт заявку на лицензию. Продукт, организация и сведения о
+// машине передаются заголовками X-License-*, details — в теле запроса.
+// Существующая заявка возвращается ошибкой *errors.LicenseRequestExistsError.
+func RequestLicense(httpClient *http.Client, serverURL, licenseKey string, details RequestDetails) (*CreateResponse, error) {
+	payload, err := json.Marshal(struct {
+		LicenseKey string `json:"license_key"`
+		RequestDetails
+	}{licenseKey, details})
+	if err != nil {
+		return nil, err
+	}
+	resp, err := httpClient.Post(strings.TrimRight(serverURL, "/")+"/api/create-license-request",
+		"application/json", bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return nil, responseError(resp)
+	}
+	var body CreateResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	return &body, nil
+}
@@ -0,0 +1,54 @@
+package licenseclient
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Backoff задаёт паузы WaitForApproval после ошибок связи: пауза растёт от
+// интервала опроса в Multiplier раз с каждой ошибкой подряд, но не больше Max
+// (0 — без ограничения). К каждой паузе добавляется случайный разброс ±Jitter
+// (доля от паузы), чтобы клиенты не обращались к серверу одновременно.
+type Backoff struct {
+	Max        time.Duration
+	Multiplier float64
+	Jitter     float64
+}
+
+// DefaultBackoff — параметры повторов по умолчанию
+var DefaultBackoff = Backoff{Max: 5 * time.Minute, Multiplier: 2, Jitter: 0.2}
+
+// WithBackoff задаёт паузы между повторами после ошибок
+func WithBackoff(b Backoff) Option {
+	return func(c *Client) { c.backoff = b }
+}
+
+// delay — пауза перед следующей проверкой после failures ошибок подряд
+// (0 — предыдущая проверка прошла успешно)
+func (b Backoff) delay(interval time.Duration, failures int) time.Duration {
+	d := interval
+	if failures > 0 && b.Multiplier > 1 {
+		f := float64(interval) * math.Pow(b.Multiplier, float64(failures))
+		if b.Max > 0 && f > float64(b.Max) {
+			f = float64(b.Max)
+		}
+		if f >= float64(math.MaxInt64) {
+			d = math.MaxInt64
+		} else {
+			d = time.Duration(f)
+		}
+	}
+	return b.jitter(d)
+}
+
+func (b Backoff) jitter(d time.Duration) time.Duration {
+	if b.Jitter <= 0 || d <= 0 {
+		return d
+	}
+	spread := float64(d) * b.Jitter
+	if float64(d)+spread >= float64(math.MaxInt64) {
+		return d
+	}
+	return d + time.Duration(spread*(2*rand.Float64()-1))
+}
@@ -0,0 +1,69 @@
+package licenseclient
+
+import (
+	"math"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	const interval = 10 * time.Second
+	tests := []struct {
+		name     string
+		backoff  Backoff
+		failures int
+		want     time.Duration
+	}{
+		{name: "success keeps interval", backoff: Backoff{Max: time.Minute, Multiplier: 2}, failures: 0, want: interval},
+		{name: "first failure", backoff: Backoff{Max: time.Minute, Multiplier: 2}, failures: 1, want: 20 * time.Second},
+		{name: "grows exponentially", backoff: Backoff{Max: time.Hour, Multiplier: 3}, failures: 3, want: 270 * time.Second},
+		{name: "capped by max", backoff: Backoff{Max: time.Minute, Multiplier: 2}, failures: 5, want: time.Minute},
+		{name: "multiplier 1 disables growth", backoff: Backoff{Max: time.Minute, Multiplier: 1}, failures: 5, want: interval},
+		{name: "no max does not overflow", backoff: Backoff{Multiplier: 2}, failures: 1000, want: math.MaxInt64},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.backoff.delay(interval, tt.failures); got != tt.want {
+				t.Errorf("delay(%s, %d) = %s, want %s", interval, tt.failures, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackoffJitter(t *testing.T) {
+	b := Backoff{Max: time.Minute, Multiplier: 2, Jitter: 0.2}
+	// 20s ±20% — от 16s до 24s
+	for i := 0; i < 1000; i++ {
+		if got := b.delay(10*time.Second, 1); got < 16*time.Second || got > 24*time.Second {
+			t.Fatalf("delay() = %s, want within [16s, 24s]", got)
+		}
+	}
+	big := Backoff{Multiplier: 2, Jitter: 0.5}
+	if got := big.delay(time.Second, 1000); got <= 0 {
+		t.Errorf("delay() with jitter overflowed to %s", got)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{value: "", want: 0},
+		{value: " 120 ", want: 2 * time.Minute},
+		{value: "0", want: 0},
+		{value: "-5", want: 0},
+		{value: "86400", want: maxRetryAfter},
+		{value: now.Add(90 * time.Second).Format(http.TimeFormat), want: 90 * time.Second},
+		{value: now.Add(-time.Minute).Format(http.TimeFormat), want: 0},
+		{value: now.Add(48 * time.Hour).Format(http.TimeFormat), want: maxRetryAfter},
+		{value: "soon", want: 0},
+	}
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.value, now); got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,344 @@
+package licenseclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	licerrors "example.com/licence-approval/client/pkg/errors"
+	"example.com/licence-approval/client/pkg/handlers"
+	"example.com/licence-approval/client/pkg/licensefile"
+)
+
+// Status — состояние лицензии с точки зрения клиента
+type Status string
+
+const (
+	StatusActive      Status = "active"
+	StatusPending     Status = "pending"
+	StatusRejected    Status = "rejected"
+	StatusNotActive   Status = "not_active" // заявки нет — её нужно создать
+	StatusExpired     Status = "expired"
+	StatusDeactivated Status = "deactivated"
+	StatusTransferred Status = "transferred"
+	// StatusOffline — сервер недоступен, лицензия действует по кэшу до OfflineUntil
+	StatusOffline Status = "offline"
+)
+
+// Сообщения сервера, по которым определяется статус, если сервер не сообщает
+// поле status (версии до структурированных ответов)
+var statusMessages = map[string]Status{
+	"License is active.":                               StatusActive,
+	"License request is pending.":                      StatusPending,
+	"License request has been rejected.":               StatusRejected,
+	"Trial license has expired.":                       StatusExpired,
+	"License has been deactivated.":                    StatusDeactivated,
+	"License has been transferred to another machine.": StatusTransferred,
+}
+
+// serverStatuses — значения поля status в ответах сервера
+var serverStatuses = map[string]Status{
+	handlers.StatusActive:      StatusActive,
+	handlers.StatusPending:     StatusPending,
+	handlers.StatusRejected:    StatusRejected,
+	handlers.StatusNotActive:   StatusNotActive,
+	handlers.StatusExpired:     StatusExpired,
+	handlers.StatusDeactivated: StatusDeactivated,
+	handlers.StatusTransferred: StatusTransferred,
+}
+
+var ErrWaitTimeout = errors.New("timed out waiting for license approval")
+
+// Result — результат проверки лицензии
+type Result struct {
+	Status  Status
+	Message string
+	// Время сервера из последнего ответа; нулевое в режиме offline
+	ServerTime   time.Time
+	OfflineUntil time.Time
+	// Ошибка связи с сервером, из-за которой включён режим offline
+	OfflineReason error
+	// Лицензия из файла офлайн-активации (см. WithLicenseFile)
+	Activation *licensefile.License
+	// Когда сервер советует проверить снова (заголовок Retry-After); 0 — без подсказки
+	RetryAfter time.Duration
+
+	// Уточнение статуса от сервера, например trial_expired или policy
+	Reason    string
+	RequestID int
+	CreatedAt time.Time
+	DecidedAt time.Time
+	ExpiresAt time.Time
+}
+
+// Licensed сообщает, можно ли продолжать работу
+func (r *Result) Licensed() bool {
+	return r.Status == StatusActive || r.Status == StatusOffline
+}
+
+// Final сообщает, что статус не изменится без действий пользователя или администратора
+func (r *Result) Final() bool {
+	return r.Status != StatusPending && r.Status != StatusNotActive
+}
+
+// RequestResult — результат создания заявки
+type RequestResult struct {
+	RequestID int
+	// Заявка с этим ключом уже существовала
+	Existing bool
+	// Статус новой заявки: правила сервера могут сразу одобрить или отклонить её
+	Status Status
+	Reason string
+}
+
+// Check запрашивает статус лицензии. Если сервер недоступен, принимается
+// лицензия офлайн-активации, а при её отсутствии — кэш: StatusOffline,
+// пока не истёк grace period.
+func (c *Client) Check(ctx context.Context) (res *Result, err error) {
+	ctx, span := c.startSpan(ctx, "Check")
+	defer func() { endSpan(span, res, err) }()
+
+	res, _, err = c.check(ctx)
+	return res, err
+}
+
+// check выполняет Check и возвращает подсказку сервера о повторе — в том числе
+// при ошибке, например из ответа 503
+func (c *Client) check(ctx context.Context) (*Result, time.Duration, error) {
+	hc, call := c.call(ctx)
+	st, err := handlers.CheckStatus(hc, c.serverURL, c.licenseKey)
+	retryAfter := call.retryAfter
+
+	var res *Result
+	var signed *licensefile.File
+	fromServer := true
+	switch p := licerrors.ProblemOf(err); {
+	case err == nil:
+		if retryAfter == 0 && st.RetryAfter > 0 {
+			retryAfter = time.Duration(st.RetryAfter) * time.Second
+		}
+		res = resultFromStatus(st)
+		res.ServerTime = c.serverTime.Last()
+		res.RetryAfter = retryAfter
+		if res.Status == StatusActive && st.License != "" && st.Signature != "" {
+			signed = &licensefile.File{License: st.License, Signature: st.Signature}
+		}
+	case isRejected(err):
+		res = &Result{Status: StatusRejected, Message: err.Error(), ServerTime: c.serverTime.Last()}
+	case p != nil && p.Status < 500:
+		// Сервер ответил и отказал — режим offline здесь не поможет
+		return nil, retryAfter, fmt.Errorf("check license: %w", err)
+	default:
+		if ctx.Err() != nil {
+			return nil, 0, ctx.Err()
+		}
+		fromServer = false
+		if fileRes, ok := c.fileActivation(err); ok {
+			res = fileRes
+		} else if res, err = c.offline(err); err != nil {
+			return nil, retryAfter, err
+		}
+	}
+
+	// Сертификат для взаимного TLS выдаётся вместе с лицензией
+	if fromServer && res.Status == StatusActive && c.needCertificate() {
+		if err := c.refreshCertificate(ctx); err != nil {
+			c.reportError(fmt.Errorf("client certificate: %w", err))
+		}
+	}
+
+	if fromServer && c.cache != nil {
+		// Кэшируется подписанная лицензия; ошибка записи кэша не мешает работе —
+		// лишь лишает режима offline
+		if err := c.cache.Record(c.licenseKey, signed, time.Now()); err != nil {
+			c.reportError(fmt.Errorf("save license cache: %w", err))
+		}
+	}
+	c.notify(res)
+	return res, retryAfter, nil
+}
+
+// offline решает, можно ли работать по кэшу после ошибки связи checkErr.
+// Лицензия в кэше проверяется открытым ключом продукта (см. WithProductKey).
+func (c *Client) offline(checkErr error) (*Result, error) {
+	if c.cache == nil {
+		return nil, fmt.Errorf("check license: %w", checkErr)
+	}
+	now := time.Now()
+	lic, until, err := c.cache.Offline(c.licenseKey, c.productKey, c.gracePeriod, now)
+	if err == nil {
+		err = c.checkLicense(lic, now)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("check license: %w (offline mode is not available: %v)", checkErr, err)
+	}
+	res := &Result{
+		Status:        StatusOffline,
+		Message:       "License server is unreachable.",
+		OfflineUntil:  until,
+		OfflineReason: checkErr,
+	}
+	if lic.ExpiresAt != nil {
+		res.ExpiresAt = *lic.ExpiresAt
+	}
+	return res, nil
+}
+
+// Request создаёт заявку на лицензию. Существующая заявка не считается ошибкой.
+func (c *Client) Request(ctx context.Context) (_ *RequestResult, err error) {
+	ctx, span := c.startSpan(ctx, "Request")
+	defer func() { endSpan(span, nil, err) }()
+
+	resp, err := handlers.RequestLicense(c.withContext(ctx), c.serverURL, c.licenseKey, c.details)
+	if err != nil {
+		var exists *licerrors.LicenseRequestExistsError
+		if errors.As(err, &exists) {
+			return &RequestResult{RequestID: exists.RequestID, Existing: true}, nil
+		}
+		return nil, fmt.Errorf("create license request: %w", err)
+	}
+	status := serverStatuses[resp.Status]
+	if status == "" {
+		status = StatusPending
+	}
+	return &RequestResult{RequestID: resp.RequestID, Status: status, Reason: resp.Reason}, nil
+}
+
+// WaitForApproval проверяет лицензию с интервалом WithPollInterval, пока статус
+// не станет окончательным (см. Result.Final). Ошибки связи передаются в OnError
+// и не прерывают ожидание: паузы после них растут по WithBackoff. Подсказка
+// сервера Retry-After удлиняет паузу. По истечении WithMaxWait возвращает
+// ErrWaitTimeout; при WithMaxWait(0) ждёт, пока не будет отменён ctx.
+func (c *Client) WaitForApproval(ctx context.Context) (res *Result, err error) {
+	ctx, span := c.startSpan(ctx, "WaitForApproval")
+	defer func() { endSpan(span, res, err) }()
+
+	if c.maxWait > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.maxWait)
+		defer cancel()
+	}
+
+	failures := 0
+	for {
+		last, retryAfter, err := c.check(ctx)
+		if err == nil {
+			if last.Final() {
+				return last, nil
+			}
+			res = last
+			failures = 0
+		} else {
+			failures++
+		}
+
+		delay := c.backoff.delay(c.pollInterval, failures)
+		if retryAfter > delay {
+			delay = retryAfter
+		}
+		if err != nil && ctx.Err() == nil {
+			c.reportError(fmt.Errorf("%w (retrying in %s)", err, delay.Round(time.Second)))
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return res, ErrWaitTimeout
+			}
+			return res, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// Deactivate освобождает активацию лицензии на этой машине
+func (c *Client) Deactivate(ctx context.Context) (err error) {
+	ctx, span := c.startSpan(ctx, "Deactivate")
+	defer func() { endSpan(span, nil, err) }()
+
+	if err := handlers.DeactivateLicense(c.withContext(ctx), c.serverURL, c.licenseKey); err != nil {
+		return fmt.Errorf("deactivate license: %w", err)
+	}
+	c.notify(&Result{Status: StatusDeactivated, Message: "License has been deactivated."})
+	return nil
+}
+
+// License получает подписанную лицензию; её можно сохранить и проверять без сервера
+func (c *Client) License(ctx context.Context) (_ *licensefile.File, err error) {
+	ctx, span := c.startSpan(ctx, "License")
+	defer func() { endSpan(span, nil, err) }()
+
+	f, err := handlers.FetchLicense(c.withContext(ctx), c.serverURL, c.licenseKey)
+	if err != nil {
+		return nil, fmt.Errorf("fetch license: %w", err)
+	}
+	return f, nil
+}
+
+// LastStatus возвращает последний наблюдавшийся статус (пусто, если проверок не было)
+func (c *Client) LastStatus() Status {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lastStatus
+}
+
+// notify запоминает статус и вызывает OnStatusChange, если он изменился
+func (c *Client) notify(res *Result) {
+	c.mu.Lock()
+	prev := c.lastStatus
+	c.lastStatus = res.Status
+	c.mu.Unlock()
+
+	if prev != res.Status && c.onStatusChange != nil {
+		c.onStatusChange(prev, res)
+	}
+}
+
+func (c *Client) reportError(err error) {
+	if c.onError != nil {
+		c.onError(err)
+	}
+}
+
+// resultFromStatus переводит ответ сервера в Result; без поля status статус
+// определяется по тексту сообщения
+func resultFromStatus(st *handlers.StatusResponse) *Result {
+	status, ok := serverStatuses[st.Status]
+	if !ok {
+		status = statusFromMessage(st.HasLicense, st.Message)
+	}
+	res := &Result{
+		Status:    status,
+		Message:   st.Message,
+		Reason:    st.Reason,
+		RequestID: st.RequestID,
+	}
+	if st.CreatedAt != nil {
+		res.CreatedAt = *st.CreatedAt
+	}
+	if st.DecidedAt != nil {
+		res.DecidedAt = *st.DecidedAt
+	}
+	if st.ExpiresAt != nil {
+		res.ExpiresAt = *st.ExpiresAt
+	}
+	return res
+}
+
+func statusFromMessage(hasLicense bool, message string) Status {
+	if hasLicense {
+		return StatusActive
+	}
+	if s, ok := statusMessages[message]; ok {
+		return s
+	}
+	return StatusNotActive
+}
+
+func isRejected(err error) bool {
+	var rejected *licerrors.LicenseRejectedError
+	return errors.As(err, &rejected)
+}
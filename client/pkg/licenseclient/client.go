@@ -0,0 +1,305 @@
+// Package licenseclient — встраиваемый клиент сервера лицензий: проверка лицензии,
+// создание заявки и ожидание одобрения с поддержкой context.Context.
+//
+//	lc, err := licenseclient.New(serverURL, licenseKey,
+//		licenseclient.WithProduct("editor", "2.1"),
+//		licenseclient.OnStatusChange(func(prev licenseclient.Status, res *licenseclient.Result) { ... }),
+//	)
+//	res, err := lc.Check(ctx)
+package licenseclient
+
+import (
+	"crypto/rsa"
+	"crypto/tls"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"example.com/licence-approval/client/pkg/cache"
+	"example.com/licence-approval/client/pkg/handlers"
+
+	"github.com/denisbrodbeck/machineid"
+)
+
+// Значения по умолчанию
+const (
+	DefaultTimeout      = 10 * time.Second
+	DefaultPollInterval = 10 * time.Second
+	DefaultMaxWait      = 5 * time.Minute
+	DefaultGracePeriod  = 72 * time.Hour
+)
+
+// appID — соль для отпечатка машины, чтобы не передавать серверу сырой machine-id
+const appID = "licence-approval"
+
+var (
+	ErrNoServerURL  = errors.New("license server URL is not set")
+	ErrNoLicenseKey = errors.New("license key is not set")
+)
+
+// Client — клиент сервера лицензий. Безопасен для использования из нескольких горутин.
+type Client struct {
+	serverURL  string
+	licenseKey string
+
+	httpClient *http.Client
+	tlsConfig  *tls.Config
+	headers    map[string]string
+	details    handlers.RequestDetails
+	serverTime *cache.DateTracker
+
+	cache       *cache.Store
+	gracePeriod time.Duration
+
+	licenseFile string
+	productKey  *rsa.PublicKey
+
+	// Взаимный TLS: файлы сертификата и ключа клиента
+	certFile  string
+	keyFile   string
+	issueCert bool
+	closeIdle func()
+
+	// Пины открытых ключей сервера и прокси (см. WithPinnedKeys, WithProxy)
+	pins  []string
+	proxy string
+
+	pollInterval time.Duration
+	maxWait      time.Duration
+	backoff      Backoff
+
+	onStatusChange func(prev Status, res *Result)
+	onError        func(err error)
+	logger         *slog.Logger
+
+	mu              sync.Mutex
+	lastStatus      Status
+	clientCert      *tls.Certificate
+	certUnavailable bool
+}
+
+// Option настраивает Client
+type Option func(*Client)
+
+// WithTLSConfig задаёт TLS-настройки (доверенные CA и т. п.)
+func WithTLSConfig(cfg *tls.Config) Option {
+	return func(c *Client) { c.tlsConfig = cfg }
+}
+
+// WithHTTPClient задаёт собственный http.Client; его Transport будет обёрнут
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.httpClient = hc }
+}
+
+// WithCache включает кэш последней подписанной лицензии и работу без сервера
+// в течение grace. Нужен открытый ключ продукта (WithProductKey или WithLicenseFile).
+func WithCache(store *cache.Store, grace time.Duration) Option {
+	return func(c *Client) {
+		c.cache = store
+		c.gracePeriod = grace
+	}
+}
+
+// WithPollInterval задаёт интервал проверок в WaitForApproval
+func WithPollInterval(d time.Duration) Option {
+	return func(c *Client) { c.pollInterval = d }
+}
+
+// WithMaxWait ограничивает WaitForApproval; 0 — ждать без ограничения
+func WithMaxWait(d time.Duration) Option {
+	return func(c *Client) { c.maxWait = d }
+}
+
+// WithProduct указывает продукт (и версию), для которого нужна лицензия
+func WithProduct(code, version string) Option {
+	return func(c *Client) {
+		c.setHeader("X-License-Product", code)
+		c.setHeader("X-License-Product-Version", version)
+	}
+}
+
+// WithOrganization передаёт заявленный идентификатор организации
+func WithOrganization(claim string) Option {
+	return func(c *Client) { c.setHeader("X-License-Organization", claim) }
+}
+
+// WithInviteCode передаёт код приглашения организации
+func WithInviteCode(code string) Option {
+	return func(c *Client) { c.setHeader("X-License-Invite-Code", code) }
+}
+
+// WithRequesterEmail передаёт email запрашивающего
+func WithRequesterEmail(email string) Option {
+	return func(c *Client) { c.setHeader("X-License-Requester-Email", email) }
+}
+
+// WithRequesterName передаёт имя запрашивающего
+func WithRequesterName(name string) Option {
+	return func(c *Client) { c.details.RequesterName = name }
+}
+
+// WithJustification передаёт обоснование заявки для администратора
+func WithJustification(text string) Option {
+	return func(c *Client) { c.details.Justification = text }
+}
+
+// WithAppVersion передаёт версию приложения, встроившего клиент
+func WithAppVersion(version string) Option {
+	return func(c *Client) { c.details.AppVersion = version }
+}
+
+// WithMetadata добавляет к заявке произвольные пары ключ/значение;
+// повторные вызовы дополняют уже заданные
+func WithMetadata(metadata map[string]string) Option {
+	return func(c *Client) {
+		if len(metadata) == 0 {
+			return
+		}
+		if c.details.Metadata == nil {
+			c.details.Metadata = make(map[string]string, len(metadata))
+		}
+		for k, v := range metadata {
+			c.details.Metadata[k] = v
+		}
+	}
+}
+
+// WithTrial запрашивает пробную лицензию вместо обычной заявки
+func WithTrial() Option {
+	return func(c *Client) { c.setHeader("X-License-Trial", "1") }
+}
+
+// OnStatusChange вызывается, когда статус лицензии отличается от предыдущего
+// наблюдавшегося (в том числе при первой проверке)
+func OnStatusChange(fn func(prev Status, res *Result)) Option {
+	return func(c *Client) { c.onStatusChange = fn }
+}
+
+// WithLogger задаёт журнал HTTP-запросов клиента (уровень debug);
+// по умолчанию — slog.Default()
+func WithLogger(l *slog.Logger) Option {
+	return func(c *Client) { c.logger = l }
+}
+
+// OnError вызывается при ошибках, которые WaitForApproval пропускает и повторяет
+func OnError(fn func(err error)) Option {
+	return func(c *Client) { c.onError = fn }
+}
+
+// New создаёт клиент для сервера serverURL и ключа licenseKey. Пустой serverURL
+// допустим для машин без сети: тогда работают только методы офлайн-активации.
+func New(serverURL, licenseKey string, opts ...Option) (*Client, error) {
+	if licenseKey == "" {
+		return nil, ErrNoLicenseKey
+	}
+
+	c := &Client{
+		serverURL:    strings.TrimRight(serverURL, "/"),
+		licenseKey:   licenseKey,
+		headers:      make(map[string]string),
+		gracePeriod:  DefaultGracePeriod,
+		pollInterval: DefaultPollInterval,
+		maxWait:      DefaultMaxWait,
+		backoff:      DefaultBackoff,
+	}
+	c.setMachineHeaders()
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.pollInterval <= 0 {
+		c.pollInterval = DefaultPollInterval
+	}
+	if c.certFile != "" {
+		if err := c.setupClientCertificate(); err != nil {
+			return nil, err
+		}
+	}
+
+	// Цепочка: заголовки X-License-* → X-Request-ID и журнал → спан HTTP-запроса
+	// и traceparent → учёт времени сервера → базовый транспорт
+	base := http.DefaultTransport
+	timeout := DefaultTimeout
+	if c.httpClient != nil {
+		if len(c.pins) > 0 || c.proxy != "" {
+			return nil, ErrTransportOptions
+		}
+		if c.httpClient.Transport != nil {
+			base = c.httpClient.Transport
+		}
+		timeout = c.httpClient.Timeout
+	} else {
+		t, err := c.transport()
+		if err != nil {
+			return nil, err
+		}
+		base = t
+		c.closeIdle = t.CloseIdleConnections
+	}
+	if c.logger == nil {
+		c.logger = slog.Default()
+	}
+	c.serverTime = &cache.DateTracker{Base: base}
+	c.httpClient = &http.Client{
+		Timeout: timeout,
+		Transport: &headerTransport{
+			base:    &requestLogTransport{base: tracingTransport(c.serverTime), logger: c.logger},
+			headers: c.headers,
+		},
+	}
+	return c, nil
+}
+
+// transport создаёт транспорт с настройками TLS, пинами и прокси
+func (c *Client) transport() (*http.Transport, error) {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	proxy, err := proxyFunc(c.proxy)
+	if err != nil {
+		return nil, err
+	}
+	t.Proxy = proxy
+
+	pins, err := parsePins(c.pins)
+	if err != nil {
+		return nil, err
+	}
+	if c.tlsConfig != nil || len(pins) > 0 {
+		cfg := &tls.Config{}
+		if c.tlsConfig != nil {
+			cfg = c.tlsConfig.Clone()
+		}
+		if len(pins) > 0 {
+			cfg.VerifyConnection = pinVerifier(pins)
+		}
+		t.TLSClientConfig = cfg
+	}
+	return t, nil
+}
+
+// LicenseKey возвращает ключ лицензии клиента
+func (c *Client) LicenseKey() string {
+	return c.licenseKey
+}
+
+func (c *Client) setHeader(name, value string) {
+	if value != "" {
+		c.headers[name] = value
+	}
+}
+
+// setMachineHeaders передаёт имя хоста и отпечаток машины; ОС и архитектура
+// уходят в теле заявки
+func (c *Client) setMachineHeaders() {
+	c.details.OS = runtime.GOOS
+	c.details.Arch = runtime.GOARCH
+	if hostname, err := os.Hostname(); err == nil {
+		c.setHeader("X-License-Hostname", hostname)
+	}
+	if fingerprint, err := machineid.ProtectedID(appID); err == nil {
+		c.setHeader("X-Machine-Fingerprint", fingerprint)
+	}
+}
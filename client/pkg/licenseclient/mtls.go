@@ -0,0 +1,254 @@
+package licenseclient
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"example.com/licence-approval/client/pkg/handlers"
+)
+
+// certRenewBefore — за сколько до истечения клиент запрашивает новый сертификат
+// (но не раньше, чем пройдут две трети срока его действия)
+const certRenewBefore = 7 * 24 * time.Hour
+
+// WithClientCertificate включает взаимный TLS с готовым сертификатом клиента
+// (например, выпущенным корпоративным CA). keyFile может совпадать с certFile,
+// если ключ лежит в том же PEM.
+func WithClientCertificate(certFile, keyFile string) Option {
+	return func(c *Client) {
+		c.certFile = certFile
+		c.keyFile = keyFile
+	}
+}
+
+// WithIssuedCertificate включает взаимный TLS с сертификатом, который выдаёт
+// сервер лицензий: клиент создаёт ключ в keyFile, отправляет запрос на
+// сертификат вместе с заявкой и после одобрения сохраняет сертификат в certFile.
+func WithIssuedCertificate(certFile, keyFile string) Option {
+	return func(c *Client) {
+		c.certFile = certFile
+		c.keyFile = keyFile
+		c.issueCert = true
+	}
+}
+
+// setupClientCertificate загружает сертификат и подключает его к TLS
+func (c *Client) setupClientCertificate() error {
+	if c.httpClient != nil {
+		return ErrTransportOptions
+	}
+	if c.keyFile == "" {
+		c.keyFile = c.certFile
+	}
+
+	if cert, err := loadCertificate(c.certFile, c.keyFile); err == nil {
+		c.clientCert = cert
+	} else if !c.issueCert {
+		return fmt.Errorf("load client certificate: %w", err)
+	}
+
+	if c.issueCert {
+		key, err := loadOrCreateClientKey(c.keyFile)
+		if err != nil {
+			return err
+		}
+		csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+			Subject: pkix.Name{CommonName: c.licenseKey},
+		}, key)
+		if err != nil {
+			return fmt.Errorf("create certificate request: %w", err)
+		}
+		c.setHeader("X-License-Client-CSR", base64.StdEncoding.EncodeToString(csr))
+	}
+
+	cfg := &tls.Config{}
+	if c.tlsConfig != nil {
+		cfg = c.tlsConfig.Clone()
+	}
+	cfg.GetClientCertificate = c.getClientCertificate
+	c.tlsConfig = cfg
+	return nil
+}
+
+// getClientCertificate отдаёт текущий сертификат; пока его нет или он истёк,
+// соединение устанавливается без сертификата
+func (c *Client) getClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.clientCert == nil || time.Now().After(c.clientCert.Leaf.NotAfter) {
+		return &tls.Certificate{}, nil
+	}
+	return c.clientCert, nil
+}
+
+// needCertificate — сертификата от сервера ещё нет или он скоро истекает
+func (c *Client) needCertificate() bool {
+	if !c.issueCert {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.certUnavailable {
+		return false
+	}
+	if c.clientCert == nil {
+		return true
+	}
+	leaf := c.clientCert.Leaf
+	renew := certRenewBefore
+	if third := leaf.NotAfter.Sub(leaf.NotBefore) / 3; third < renew {
+		renew = third
+	}
+	return time.Now().Add(renew).After(leaf.NotAfter)
+}
+
+// refreshCertificate получает сертификат, выданный сервером, и сохраняет его
+func (c *Client) refreshCertificate(ctx context.Context) error {
+	certPEM, err := handlers.FetchClientCertificate(c.withContext(ctx), c.serverURL, c.licenseKey)
+	if errors.Is(err, handlers.ErrNoClientCertificate) {
+		// Сервер не выпускает сертификаты — не спрашиваем при каждой проверке
+		c.mu.Lock()
+		c.certUnavailable = true
+		c.mu.Unlock()
+	}
+	if err != nil {
+		return err
+	}
+	keyPEM, err := os.ReadFile(c.keyFile)
+	if err != nil {
+		return fmt.Errorf("read client key: %w", err)
+	}
+	cert, err := parseCertificate(certPEM, keyPEM)
+	if err != nil {
+		return fmt.Errorf("client certificate from server: %w", err)
+	}
+
+	c.mu.Lock()
+	same := c.clientCert != nil && c.clientCert.Leaf.Equal(cert.Leaf)
+	c.mu.Unlock()
+	if same {
+		return nil
+	}
+	if err := writeFile(c.certFile, certPEM, 0o644); err != nil {
+		return fmt.Errorf("save client certificate: %w", err)
+	}
+
+	c.mu.Lock()
+	c.clientCert = cert
+	c.mu.Unlock()
+	// Открытые соединения установлены без сертификата
+	if c.closeIdle != nil {
+		c.closeIdle()
+	}
+	return nil
+}
+
+func loadCertificate(certFile, keyFile string) (*tls.Certificate, error) {
+	certPEM, err := os.ReadFile(certFile)
+	if err != nil {
+		return nil, err
+	}
+	keyPEM, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	return parseCertificate(certPEM, keyPEM)
+}
+
+func parseCertificate(certPEM, keyPEM []byte) (*tls.Certificate, error) {
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, err
+	}
+	if cert.Leaf == nil {
+		if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+			return nil, err
+		}
+	}
+	return &cert, nil
+}
+
+// loadOrCreateClientKey читает ключ ECDSA P-256 из path или создаёт его
+func loadOrCreateClientKey(path string) (*ecdsa.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			return nil, err
+		}
+		data = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			return nil, err
+		}
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		if errors.Is(err, os.ErrExist) {
+			// Ключ только что создал другой процесс
+			return loadOrCreateClientKey(path)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("save client key %s: %w", path, err)
+		}
+		_, err = f.Write(data)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return nil, fmt.Errorf("save client key %s: %w", path, err)
+		}
+		return key, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return nil, fmt.Errorf("no private key in %s", path)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse client key %s: %w", path, err)
+	}
+	key, ok := parsed.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("client key %s is not an ECDSA key", path)
+	}
+	return key, nil
+}
+
+// writeFile записывает файл через временный, чтобы не оставить его недописанным
+func writeFile(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,109 @@
+package licenseclient
+
+import (
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"time"
+
+	"example.com/licence-approval/client/pkg/activation"
+	"example.com/licence-approval/client/pkg/licensefile"
+)
+
+var ErrNoLicenseFile = errors.New("license file path or product public key is not configured")
+
+// WithLicenseFile задаёт файл лицензии офлайн-активации и открытый ключ продукта.
+// Если сервер недоступен, Check принимает действующую лицензию из этого файла.
+func WithLicenseFile(path string, pub *rsa.PublicKey) Option {
+	return func(c *Client) {
+		c.licenseFile = path
+		c.productKey = pub
+	}
+}
+
+// WithProductKey задаёт открытый ключ продукта для проверки подписанной
+// лицензии в кэше (см. WithCache)
+func WithProductKey(pub *rsa.PublicKey) Option {
+	return func(c *Client) { c.productKey = pub }
+}
+
+// ActivationRequest — заявка на офлайн-активацию с данными этой установки
+func (c *Client) ActivationRequest() *activation.Request {
+	return &activation.Request{
+		LicenseKey:     c.licenseKey,
+		Fingerprint:    c.headers["X-Machine-Fingerprint"],
+		Hostname:       c.headers["X-License-Hostname"],
+		Product:        c.headers["X-License-Product"],
+		ProductVersion: c.headers["X-License-Product-Version"],
+		Organization:   c.headers["X-License-Organization"],
+		InviteCode:     c.headers["X-License-Invite-Code"],
+		RequesterEmail: c.headers["X-License-Requester-Email"],
+		CreatedAt:      time.Now().UTC(),
+		RequesterName:  c.details.RequesterName,
+		Justification:  c.details.Justification,
+		OS:             c.details.OS,
+		Arch:           c.details.Arch,
+		AppVersion:     c.details.AppVersion,
+		Metadata:       c.details.Metadata,
+	}
+}
+
+// ImportActivation проверяет ответ администратора и сохраняет лицензию в файл WithLicenseFile
+func (c *Client) ImportActivation(resp *activation.ResponseFile) (*licensefile.License, error) {
+	if c.licenseFile == "" || c.productKey == nil {
+		return nil, ErrNoLicenseFile
+	}
+	f := resp.LicenseFile()
+	lic, err := c.verifyLicense(f)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.Save(c.licenseFile); err != nil {
+		return nil, fmt.Errorf("save license file: %w", err)
+	}
+	c.notify(&Result{Status: StatusActive, Message: "License is activated from file.", Activation: lic})
+	return lic, nil
+}
+
+// fileActivation возвращает результат по файлу офлайн-активации
+func (c *Client) fileActivation(checkErr error) (*Result, bool) {
+	if c.licenseFile == "" || c.productKey == nil {
+		return nil, false
+	}
+	f, err := licensefile.Load(c.licenseFile)
+	if err != nil {
+		return nil, false
+	}
+	lic, err := c.verifyLicense(f)
+	if err != nil {
+		c.reportError(fmt.Errorf("license file %s: %w", c.licenseFile, err))
+		return nil, false
+	}
+	return &Result{
+		Status:        StatusActive,
+		Message:       "License is activated from file.",
+		OfflineReason: checkErr,
+		Activation:    lic,
+	}, true
+}
+
+// verifyLicense проверяет подпись и привязку лицензии к ключу, машине и продукту
+func (c *Client) verifyLicense(f *licensefile.File) (*licensefile.License, error) {
+	lic, err := f.Verify(c.productKey)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.checkLicense(lic, time.Now()); err != nil {
+		return nil, err
+	}
+	return lic, nil
+}
+
+// checkLicense проверяет, что проверенная по подписи лицензия выдана этой
+// установке: ключ лицензии, машина, продукт и срок действия
+func (c *Client) checkLicense(lic *licensefile.License, now time.Time) error {
+	if err := lic.Check(c.licenseKey, c.headers["X-Machine-Fingerprint"], now); err != nil {
+		return err
+	}
+	return lic.CheckProduct(c.headers["X-License-Product"])
+}
@@ -0,0 +1,82 @@
+package licenseclient
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Трассировка идёт через глобальные провайдер и пропагатор OpenTelemetry
+// (otel.SetTracerProvider, otel.SetTextMapPropagator): приложение, которое их
+// настроило, видит операции клиента и его HTTP-запросы, а сервер продолжает
+// ту же трассировку по заголовку traceparent. Без настройки спаны не пишутся.
+var tracer = otel.Tracer("example.com/licence-approval/client/pkg/licenseclient")
+
+// startSpan открывает спан операции клиента; HTTP-запросы внутри неё
+// становятся дочерними спанами
+func (c *Client) startSpan(ctx context.Context, operation string) (context.Context, trace.Span) {
+	attrs := []attribute.KeyValue{attribute.String("server.url", c.serverURL)}
+	if product := c.headers["X-License-Product"]; product != "" {
+		attrs = append(attrs, attribute.String("license.product", product))
+	}
+	return tracer.Start(ctx, "licenseclient."+operation, trace.WithAttributes(attrs...))
+}
+
+// endSpan завершает спан операции, отмечая статус лицензии и ошибку
+func endSpan(span trace.Span, res *Result, err error) {
+	if res != nil {
+		span.SetAttributes(attribute.String("license.status", string(res.Status)))
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// tracingTransport открывает спан на каждый HTTP-запрос («GET /api/check-license»)
+// и передаёт серверу контекст трассировки в заголовке traceparent. otelhttp
+// записывает в спан полный URL, а в параметрах запроса бывает ключ лицензии,
+// поэтому инструментация видит URL без параметров, а на сервер уходит исходный.
+func tracingTransport(base http.RoundTripper) http.RoundTripper {
+	traced := otelhttp.NewTransport(&restoreQueryTransport{base: base},
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			return r.Method + " " + r.URL.Path
+		}))
+	return &stripQueryTransport{base: traced}
+}
+
+type queryKey struct{}
+
+// stripQueryTransport убирает параметры из URL, сохраняя их в контексте запроса
+type stripQueryTransport struct {
+	base http.RoundTripper
+}
+
+func (t *stripQueryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.RawQuery == "" {
+		return t.base.RoundTrip(req)
+	}
+	query := req.URL.RawQuery
+	req = req.Clone(context.WithValue(req.Context(), queryKey{}, query))
+	req.URL.RawQuery = ""
+	return t.base.RoundTrip(req)
+}
+
+// restoreQueryTransport возвращает параметры, убранные stripQueryTransport
+type restoreQueryTransport struct {
+	base http.RoundTripper
+}
+
+func (t *restoreQueryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if query, ok := req.Context().Value(queryKey{}).(string); ok {
+		req = req.Clone(req.Context())
+		req.URL.RawQuery = query
+	}
+	return t.base.RoundTrip(req)
+}
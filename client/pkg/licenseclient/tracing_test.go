@@ -0,0 +1,49 @@
+package licenseclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracingTransportOmitsQuery(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+
+	var gotQuery string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotQuery = r.URL.RawQuery
+	}))
+	defer srv.Close()
+
+	hc := &http.Client{Transport: tracingTransport(http.DefaultTransport)}
+	resp, err := hc.Get(srv.URL + "/api/check-license?license_key=SECRET-LICENSE-KEY")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if gotQuery != "license_key=SECRET-LICENSE-KEY" {
+		t.Errorf("server got query %q, want the original one", gotQuery)
+	}
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	if spans[0].Name != "GET /api/check-license" {
+		t.Errorf("span name = %q", spans[0].Name)
+	}
+	for _, attr := range spans[0].Attributes {
+		if strings.Contains(attr.Value.Emit(), "SECRET-LICENSE-KEY") {
+			t.Errorf("span attribute %s contains the license key: %s", attr.Key, attr.Value.Emit())
+		}
+	}
+}
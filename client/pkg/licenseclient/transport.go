@@ -0,0 +1,134 @@
+package licenseclient
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxRetryAfter ограничивает подсказку Retry-After: ошибочное значение
+// не должно остановить опрос на часы
+const maxRetryAfter = time.Hour
+
+// headerTransport добавляет к каждому запросу заголовки с продуктом и организацией
+type headerTransport struct {
+	base    http.RoundTripper
+	headers map[string]string
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if len(t.headers) == 0 {
+		return t.base.RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+	return t.base.RoundTrip(req)
+}
+
+// RequestIDHeader — идентификатор запроса; сервер пишет его в свой журнал
+// и возвращает в ответе, так что запрос клиента можно найти в логах сервера
+const RequestIDHeader = "X-Request-ID"
+
+// requestLogTransport присваивает каждому запросу X-Request-ID и пишет запрос
+// в журнал на уровне debug. В журнал попадает путь без параметров: в них ключ лицензии.
+type requestLogTransport struct {
+	base   http.RoundTripper
+	logger *slog.Logger
+}
+
+func (t *requestLogTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	id := newRequestID()
+	req = req.Clone(req.Context())
+	req.Header.Set(RequestIDHeader, id)
+
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	attrs := []any{
+		"method", req.Method,
+		"path", req.URL.Path,
+		"request_id", id,
+		"duration_ms", time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		t.logger.DebugContext(req.Context(), "License server request failed", append(attrs, "error", err)...)
+		return nil, err
+	}
+	t.logger.DebugContext(req.Context(), "License server request", append(attrs, "status", resp.StatusCode)...)
+	return resp, nil
+}
+
+func newRequestID() string {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
+
+// contextTransport привязывает запросы к ctx: функции пакета handlers
+// создают запросы без контекста, поэтому отмена передаётся через транспорт
+type contextTransport struct {
+	ctx  context.Context
+	base http.RoundTripper
+	// err — запросы невозможны, например не задан адрес сервера
+	err error
+	// retryAfter — подсказка сервера из последнего ответа (заголовок Retry-After)
+	retryAfter time.Duration
+}
+
+func (t *contextTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.err != nil {
+		return nil, t.err
+	}
+	resp, err := t.base.RoundTrip(req.WithContext(t.ctx))
+	if err == nil {
+		t.retryAfter = parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+	}
+	return resp, err
+}
+
+// parseRetryAfter разбирает Retry-After: число секунд или HTTP-дату
+func parseRetryAfter(v string, now time.Time) time.Duration {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0
+	}
+	var d time.Duration
+	if secs, err := strconv.Atoi(v); err == nil {
+		d = time.Duration(secs) * time.Second
+	} else if at, err := http.ParseTime(v); err == nil {
+		d = at.Sub(now)
+	}
+	if d < 0 {
+		return 0
+	}
+	if d > maxRetryAfter {
+		return maxRetryAfter
+	}
+	return d
+}
+
+// withContext возвращает http.Client, запросы которого отменяются вместе с ctx
+func (c *Client) withContext(ctx context.Context) *http.Client {
+	hc, _ := c.call(ctx)
+	return hc
+}
+
+// call — как withContext, но также возвращает транспорт, чтобы после запроса
+// прочитать подсказку сервера о повторе
+func (c *Client) call(ctx context.Context) (*http.Client, *contextTransport) {
+	hc := *c.httpClient
+	t := &contextTransport{ctx: ctx, base: c.httpClient.Transport}
+	if c.serverURL == "" {
+		t.err = ErrNoServerURL
+	}
+	hc.Transport = t
+	return &hc, t
+}
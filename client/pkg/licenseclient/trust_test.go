@@ -0,0 +1,120 @@
+package licenseclient
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// newCert выпускает сертификат name, подписанный parent (nil — самоподписанный CA)
+func newCert(t *testing.T, name string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		BasicConstraintsValid: true,
+		IsCA:                  parent == nil,
+	}
+	if parent == nil {
+		parent, parentKey = tmpl, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+func TestPinVerifier(t *testing.T) {
+	realCA, realCAKey := newCert(t, "Real CA", nil, nil)
+	server, _ := newCert(t, "license.example.com", realCA, realCAKey)
+	// Перехватывающий прокси с сертификатом от другого доверенного CA
+	proxyCA, proxyCAKey := newCert(t, "Corporate Proxy CA", nil, nil)
+	proxied, _ := newCert(t, "license.example.com", proxyCA, proxyCAKey)
+
+	tests := []struct {
+		name    string
+		pins    []string
+		state   tls.ConnectionState
+		wantErr bool
+	}{
+		{
+			name: "server key pinned",
+			pins: []string{SPKIPin(server)},
+			state: tls.ConnectionState{
+				PeerCertificates: []*x509.Certificate{server},
+				VerifiedChains:   [][]*x509.Certificate{{server, realCA}},
+			},
+		},
+		{
+			name: "CA key pinned with prefix",
+			pins: []string{"sha256//" + SPKIPin(realCA)},
+			state: tls.ConnectionState{
+				PeerCertificates: []*x509.Certificate{server},
+				VerifiedChains:   [][]*x509.Certificate{{server, realCA}},
+			},
+		},
+		{
+			name: "real certificate appended to another chain",
+			pins: []string{SPKIPin(server)},
+			state: tls.ConnectionState{
+				PeerCertificates: []*x509.Certificate{proxied, server},
+				VerifiedChains:   [][]*x509.Certificate{{proxied, proxyCA}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "one of the verified chains is not pinned",
+			pins: []string{SPKIPin(realCA)},
+			state: tls.ConnectionState{
+				PeerCertificates: []*x509.Certificate{server},
+				VerifiedChains:   [][]*x509.Certificate{{server, realCA}, {proxied, proxyCA}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "chain was not verified",
+			pins: []string{SPKIPin(server)},
+			state: tls.ConnectionState{
+				PeerCertificates: []*x509.Certificate{server},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pins, err := parsePins(tt.pins)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = pinVerifier(pins)(tt.state)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("pinVerifier() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParsePinsInvalid(t *testing.T) {
+	for _, pin := range []string{"not base64!", "c2hvcnQ="} {
+		if _, err := parsePins([]string{pin}); err == nil {
+			t.Errorf("parsePins(%q) succeeded, want error", pin)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+// Package licensefile — подписанный файл лицензии и его проверка без обращения к серверу.
+//
+// Сервер подписывает JSON лицензии ключом продукта (RSA PKCS#1 v1.5, SHA-256);
+// для проверки достаточно открытого ключа продукта.
+package licensefile
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+var (
+	ErrBadSignature = errors.New("license signature is invalid")
+	ErrKeyMismatch  = errors.New("license was issued for another license key")
+	ErrWrongMachine = errors.New("license was issued for another machine")
+	ErrWrongProduct = errors.New("license was issued for another product")
+	ErrExpired      = errors.New("license has expired")
+)
+
+// DefaultProduct — продукт, который сервер подставляет в заявки без продукта
+const DefaultProduct = "default"
+
+// File — содержимое файла лицензии: данные в том виде, в каком их подписал сервер, и подпись
+type File struct {
+	License   string `json:"license"`
+	Signature string `json:"signature"`
+}
+
+// License — поля подписанной лицензии
+type License struct {
+	LicenseKey     string                 `json:"license_key"`
+	Product        string                 `json:"product"`
+	ProductVersion string                 `json:"product_version,omitempty"`
+	Fingerprint    string                 `json:"fingerprint,omitempty"`
+	Tag            int                    `json:"tag"`
+	Entitlements   map[string]interface{} `json:"entitlements"`
+	IssuedAt       time.Time              `json:"issued_at"`
+	Trial          bool                   `json:"trial,omitempty"`
+	ExpiresAt      *time.Time             `json:"expires_at,omitempty"`
+	// Время проверки на сервере; есть только в лицензии из ответа check-license
+	CheckedAt *time.Time `json:"checked_at,omitempty"`
+}
+
+// Load читает файл лицензии
+func Load(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f File
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("parse license file %s: %w", path, err)
+	}
+	if f.License == "" || f.Signature == "" {
+		return nil, fmt.Errorf("license file %s has no license or signature", path)
+	}
+	return &f, nil
+}
+
+// Save записывает файл лицензии с правами 0600
+func (f *File) Save(path string) error {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o600)
+}
+
+// Verify проверяет подпись и возвращает содержимое лицензии
+func (f *File) Verify(pub *rsa.PublicKey) (*License, error) {
+	sig, err := base64.StdEncoding.DecodeString(f.Signature)
+	if err != nil {
+		return nil, ErrBadSignature
+	}
+	hash := sha256.Sum256([]byte(f.License))
+	if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, hash[:], sig); err != nil {
+		return nil, ErrBadSignature
+	}
+	var lic License
+	if err := json.Unmarshal([]byte(f.License), &lic); err != nil {
+		return nil, fmt.Errorf("parse license: %w", err)
+	}
+	return &lic, nil
+}
+
+// Check проверяет, что лицензия выдана на licenseKey и машину fingerprint
+// (пустые значения не проверяются) и не истекла к моменту now
+func (l *License) Check(licenseKey, fingerprint string, now time.Time) error {
+	if licenseKey != "" && l.LicenseKey != licenseKey {
+		return ErrKeyMismatch
+	}
+	if fingerprint != "" && l.Fingerprint != "" && l.Fingerprint != fingerprint {
+		return ErrWrongMachine
+	}
+	if l.ExpiresAt != nil && now.After(*l.ExpiresAt) {
+		return ErrExpired
+	}
+	return nil
+}
+
+// CheckProduct проверяет, что лицензия выдана на продукт product (пустой —
+// продукт сервера по умолчанию). Несколько продуктов могут подписываться одним
+// ключом, поэтому одной подписи недостаточно.
+func (l *License) CheckProduct(product string) error {
+	if product == "" {
+		product = DefaultProduct
+	}
+	if l.Product != product {
+		return ErrWrongProduct
+	}
+	return nil
+}
+
+// LoadPublicKey читает открытый RSA-ключ из PEM: PKIX, PKCS#1 или сертификат
+func LoadPublicKey(path string) (*rsa.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read key %s: %w", path, err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data in %s", path)
+	}
+
+	var parsed interface{}
+	switch block.Type {
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "CERTIFICATE":
+		var cert *x509.Certificate
+		if cert, err = x509.ParseCertificate(block.Bytes); err == nil {
+			parsed = cert.PublicKey
+		}
+	default:
+		err = fmt.Errorf("unsupported PEM type %q", block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse key %s: %w", path, err)
+	}
+	key, ok := parsed.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("key %s is not an RSA key", path)
+	}
+	return key, nil
+}
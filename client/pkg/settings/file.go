@@ -0,0 +1,112 @@
+package settings
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// readFile читает файл настроек YAML или JSON (по расширению).
+// Ключи — как в Known; допускаются и имена переменных окружения (LICENSE_SERVER_URL).
+func readFile(path string) (map[string]string, error) {
+	raw, err := decode(path)
+	if err != nil {
+		return nil, err
+	}
+	layer, unknown := normalize(raw)
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("%s: unknown settings: %s", path, strings.Join(unknown, ", "))
+	}
+	return layer, nil
+}
+
+// readLegacy читает прежний config.json рядом с бинарником; его отсутствие не ошибка,
+// а незнакомые ключи пропускаются
+func readLegacy(path string) (map[string]string, error) {
+	raw, err := decode(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	layer, _ := normalize(raw)
+	return layer, nil
+}
+
+func decode(path string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	raw := make(map[string]interface{})
+	if len(bytes.TrimSpace(data)) == 0 {
+		return raw, nil
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	default:
+		err = json.Unmarshal(data, &raw)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return raw, nil
+}
+
+// normalize приводит ключи к именам из Known, а значения — к строкам
+func normalize(raw map[string]interface{}) (map[string]string, []string) {
+	layer := make(map[string]string)
+	var unknown []string
+	for name, v := range raw {
+		key := lookupKey(name)
+		if key == "" {
+			unknown = append(unknown, name)
+			continue
+		}
+		switch v := v.(type) {
+		case nil:
+		case []interface{}:
+			// Списки (например, пины ключей) хранятся через запятую, как в переменных окружения
+			items := make([]string, len(v))
+			for i, item := range v {
+				items[i] = fmt.Sprint(item)
+			}
+			layer[key] = strings.Join(items, ",")
+		case map[string]interface{}:
+			// Словари (метаданные заявки) — парами key=value через запятую
+			items := make([]string, 0, len(v))
+			for k, item := range v {
+				items = append(items, k+"="+fmt.Sprint(item))
+			}
+			sort.Strings(items)
+			layer[key] = strings.Join(items, ",")
+		default:
+			layer[key] = fmt.Sprint(v)
+		}
+	}
+	return layer, unknown
+}
+
+func lookupKey(name string) string {
+	for _, s := range Known {
+		if name == s.Key || name == s.Env {
+			return s.Key
+		}
+	}
+	return ""
+}
+
+// isXDGPlatform — ОС, где принято раскладывать файлы по XDG-каталогам
+func isXDGPlatform() bool {
+	return runtime.GOOS != "windows" && runtime.GOOS != "darwin"
+}
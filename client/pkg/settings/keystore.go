@@ -0,0 +1,87 @@
+package settings
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// keyFile — файл со сгенерированным ключом лицензии в каталоге состояния
+const keyFile = "license.key"
+
+// StoredKey читает сохранённый ключ лицензии; пустая строка — ключа ещё нет
+func (s *Settings) StoredKey() (string, error) {
+	data, err := os.ReadFile(s.Path(keyFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("read license key: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// EnsureLicenseKey возвращает ключ лицензии, при необходимости создавая его
+// функцией generate. Создание выполняется под блокировкой каталога состояния,
+// поэтому параллельно запущенные клиенты получат один и тот же ключ.
+func (s *Settings) EnsureLicenseKey(generate func() (string, error)) (bool, error) {
+	if s.LicenseKey != "" {
+		return false, nil
+	}
+	if err := os.MkdirAll(s.StateDir, 0o700); err != nil {
+		return false, fmt.Errorf("create state directory: %w", err)
+	}
+
+	unlock, err := lockFile(s.Path(keyFile + ".lock"))
+	if err != nil {
+		return false, fmt.Errorf("lock state directory: %w", err)
+	}
+	defer unlock()
+
+	// Пока ждали блокировку, ключ мог создать другой процесс
+	key, err := s.StoredKey()
+	if err != nil {
+		return false, err
+	}
+	generated := false
+	if key == "" {
+		if key, err = generate(); err != nil {
+			return false, err
+		}
+		if err := WriteFileAtomic(s.Path(keyFile), []byte(key+"\n"), 0o600); err != nil {
+			return false, fmt.Errorf("save license key: %w", err)
+		}
+		generated = true
+	}
+	s.LicenseKey = key
+	s.Sources["license_key"] = s.Path(keyFile)
+	return generated, nil
+}
+
+// WriteFileAtomic записывает файл через временный файл и rename,
+// чтобы читатели никогда не увидели его частично записанным
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
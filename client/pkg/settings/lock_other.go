@@ -0,0 +1,34 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package settings
+
+import (
+	"errors"
+	"os"
+	"time"
+)
+
+// Блокировка, оставшаяся от упавшего процесса, считается устаревшей через staleLock
+const (
+	lockRetry = 50 * time.Millisecond
+	staleLock = 30 * time.Second
+)
+
+// lockFile — блокировка через эксклюзивное создание файла для ОС без flock
+func lockFile(path string) (func(), error) {
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+		if fi, statErr := os.Stat(path); statErr == nil && time.Since(fi.ModTime()) > staleLock {
+			os.Remove(path)
+			continue
+		}
+		time.Sleep(lockRetry)
+	}
+}
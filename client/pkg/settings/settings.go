@@ -0,0 +1,407 @@
+// Package settings собирает настройки клиента из нескольких слоёв.
+//
+// Приоритет (от высшего к низшему):
+//
+//  1. флаги командной строки;
+//  2. переменные окружения LICENSE_*;
+//  3. пользовательский файл: --config / LICENSE_CONFIG или
+//     $XDG_CONFIG_HOME/licence-approval/config.{yaml,yml,json};
+//  4. системный файл /etc/licence-approval/config.{yaml,yml,json};
+//  5. config.json рядом с бинарником (прежнее расположение, только чтение).
+//
+// Сгенерированный ключ лицензии хранится отдельно, в каталоге состояния
+// пользователя (см. StateDir), вместе с кэшем и файлами офлайн-активации.
+package settings
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// appName — имя каталога приложения в XDG-каталогах и /etc
+const appName = "licence-approval"
+
+// SystemConfigDir — каталог системных настроек
+var SystemConfigDir = filepath.Join("/etc", appName)
+
+// configNames — имена файлов настроек в порядке поиска
+var configNames = []string{"config.yaml", "config.yml", "config.json"}
+
+// Setting описывает один параметр: ключ в файле, переменную окружения и флаг
+type Setting struct {
+	Key   string
+	Env   string
+	Flag  string
+	Usage string
+}
+
+// Known — все поддерживаемые параметры
+var Known = []Setting{
+	{"server_url", "LICENSE_SERVER_URL", "server-url", "license server URL"},
+	{"license_key", "LICENSE_KEY", "license-key", "license key (normally generated and stored automatically)"},
+	{"product", "LICENSE_PRODUCT", "product", "product code"},
+	{"product_version", "LICENSE_PRODUCT_VERSION", "product-version", "product version"},
+	{"organization", "LICENSE_ORGANIZATION", "organization", "organization identifier"},
+	{"invite_code", "LICENSE_INVITE_CODE", "invite-code", "organization invite code"},
+	{"requester_email", "LICENSE_REQUESTER_EMAIL", "requester-email", "requester email"},
+	{"requester_name", "LICENSE_REQUESTER_NAME", "requester-name", "requester name shown to the administrator"},
+	{"justification", "LICENSE_JUSTIFICATION", "justification", "why the license is needed (shown to the administrator)"},
+	{"app_version", "LICENSE_APP_VERSION", "app-version", "version of the application requesting the license"},
+	{"metadata", "LICENSE_METADATA", "metadata", "extra request details as comma-separated key=value pairs"},
+	{"trial", "LICENSE_TRIAL", "trial", "request a trial license (true/false)"},
+	{"grace_period", "LICENSE_GRACE_PERIOD", "grace-period", "how long to work offline after the last successful check (needs public_key)"},
+	{"poll_interval", "LICENSE_POLL_INTERVAL", "poll-interval", "how often to poll the server while waiting for approval"},
+	{"max_wait", "LICENSE_MAX_WAIT", "max-wait", "give up waiting for approval after this long (0 or \"unlimited\" — wait indefinitely)"},
+	{"backoff_max", "LICENSE_BACKOFF_MAX", "backoff-max", "longest pause between retries after server errors"},
+	{"ca_cert", "LICENSE_CA_CERT", "ca-cert", "CA bundle (PEM, one or more certificates) trusted for the license server"},
+	{"system_roots", "LICENSE_SYSTEM_ROOTS", "system-roots", "also trust the system root certificates (always used when no CA bundle is found)"},
+	{"pin_sha256", "LICENSE_PIN_SHA256", "pin-sha256", "comma-separated base64 SHA-256 pins of the server public key (SPKI)"},
+	{"proxy", "LICENSE_PROXY", "proxy", "proxy URL for the license server, or \"direct\" (default: HTTPS_PROXY/NO_PROXY)"},
+	{"mtls", "LICENSE_MTLS", "mtls", "use a client certificate issued by the license server (mutual TLS)"},
+	{"client_cert", "LICENSE_CLIENT_CERT", "client-cert", "client certificate (PEM) for mutual TLS instead of one issued by the server"},
+	{"client_key", "LICENSE_CLIENT_KEY", "client-key", "private key (PEM) of --client-cert, if not in the same file"},
+	{"public_key", "LICENSE_PUBLIC_KEY", "public-key", "product public key for offline and cached license verification (PEM)"},
+	{"state_dir", "LICENSE_STATE_DIR", "state-dir", "directory for the license key, cache and activation files"},
+	{"log_level", "LICENSE_LOG_LEVEL", "log-level", "log level: debug (includes every server request), info, warn or error"},
+	{"log_format", "LICENSE_LOG_FORMAT", "log-format", "log format: text or json"},
+	{"tracing", "LICENSE_TRACING", "tracing", "export OpenTelemetry spans: \"otlp\" (see OTEL_EXPORTER_OTLP_ENDPOINT) or \"stdout\" (printed to stderr)"},
+}
+
+// Форматы журнала (параметр log_format)
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
+// Экспортёры спанов (параметр tracing)
+const (
+	TracingOTLP   = "otlp"
+	TracingStdout = "stdout"
+)
+
+// boolSettings — параметры-флажки: флаг можно задать без значения
+var boolSettings = map[string]bool{"trial": true, "mtls": true, "system_roots": true}
+
+// IsBool — параметр принимает true/false
+func (s Setting) IsBool() bool {
+	return boolSettings[s.Key]
+}
+
+// Settings — итоговые настройки клиента
+type Settings struct {
+	ServerURL      string
+	LicenseKey     string
+	Product        string
+	ProductVersion string
+	Organization   string
+	InviteCode     string
+	RequesterEmail string
+	RequesterName  string
+	Justification  string
+	AppVersion     string
+	// Metadata — произвольные пары ключ/значение для администратора
+	Metadata     map[string]string
+	Trial        bool
+	GracePeriod  time.Duration
+	PollInterval time.Duration
+	// MaxWait — 0 означает ожидание без ограничения
+	MaxWait     time.Duration
+	BackoffMax  time.Duration
+	CACert      string
+	SystemRoots bool
+	// PinSHA256 — пины открытого ключа сервера; несколько — на время смены ключа
+	PinSHA256  []string
+	Proxy      string
+	MTLS       bool
+	ClientCert string
+	ClientKey  string
+	PublicKey  string
+	StateDir   string
+	// LogLevel и LogFormat — журнал клиента (stderr)
+	LogLevel  slog.Level
+	LogFormat string
+	// Tracing — экспорт спанов OpenTelemetry: TracingOTLP, TracingStdout или пусто
+	Tracing string
+
+	// Sources — откуда взято значение каждого ключа (файл, переменная или флаг)
+	Sources map[string]string
+}
+
+// Options — входные данные для Load
+type Options struct {
+	// Flags — значения флагов, заданных явно, по ключу параметра
+	Flags map[string]string
+	// ConfigFile — пользовательский файл вместо файла в XDG-каталоге
+	ConfigFile string
+	// ExeDir — каталог бинарника: прежний config.json и пути по умолчанию
+	ExeDir string
+	// Defaults — значения по умолчанию по ключу параметра
+	Defaults map[string]string
+}
+
+// Load собирает настройки из всех слоёв
+func Load(opts Options) (*Settings, error) {
+	values := make(map[string]string)
+	sources := make(map[string]string)
+	apply := func(layer map[string]string, source string) {
+		for k, v := range layer {
+			if v != "" {
+				values[k] = v
+				sources[k] = source
+			}
+		}
+	}
+
+	apply(opts.Defaults, "default")
+
+	// Слои файлов — от низшего приоритета к высшему
+	if opts.ExeDir != "" {
+		legacy := filepath.Join(opts.ExeDir, "config.json")
+		layer, err := readLegacy(legacy)
+		if err != nil {
+			return nil, err
+		}
+		apply(layer, legacy)
+	}
+	if path := findConfig(SystemConfigDir); path != "" {
+		layer, err := readFile(path)
+		if err != nil {
+			return nil, err
+		}
+		apply(layer, path)
+	}
+
+	userFile := opts.ConfigFile
+	if userFile == "" {
+		userFile = os.Getenv("LICENSE_CONFIG")
+	}
+	if userFile != "" {
+		layer, err := readFile(userFile)
+		if err != nil {
+			return nil, err
+		}
+		apply(layer, userFile)
+	} else if dir, err := UserConfigDir(); err == nil {
+		if path := findConfig(dir); path != "" {
+			layer, err := readFile(path)
+			if err != nil {
+				return nil, err
+			}
+			apply(layer, path)
+		}
+	}
+
+	for _, s := range Known {
+		if v := os.Getenv(s.Env); v != "" {
+			values[s.Key] = v
+			sources[s.Key] = "$" + s.Env
+		}
+	}
+
+	for k, v := range opts.Flags {
+		if v != "" {
+			values[k] = v
+			sources[k] = "--" + flagName(k)
+		}
+	}
+
+	return build(values, sources)
+}
+
+func build(values, sources map[string]string) (*Settings, error) {
+	s := &Settings{
+		ServerURL:      values["server_url"],
+		LicenseKey:     values["license_key"],
+		Product:        values["product"],
+		ProductVersion: values["product_version"],
+		Organization:   values["organization"],
+		InviteCode:     values["invite_code"],
+		RequesterEmail: values["requester_email"],
+		RequesterName:  values["requester_name"],
+		Justification:  values["justification"],
+		AppVersion:     values["app_version"],
+		CACert:         values["ca_cert"],
+		Proxy:          values["proxy"],
+		ClientCert:     values["client_cert"],
+		ClientKey:      values["client_key"],
+		PublicKey:      values["public_key"],
+		StateDir:       values["state_dir"],
+		LogFormat:      values["log_format"],
+		Tracing:        values["tracing"],
+		Sources:        sources,
+	}
+	if v := values["log_level"]; v != "" {
+		if err := s.LogLevel.UnmarshalText([]byte(v)); err != nil {
+			return nil, fmt.Errorf("invalid log_level %q (from %s)", v, sources["log_level"])
+		}
+	}
+	switch s.LogFormat {
+	case "", LogFormatText, LogFormatJSON:
+	default:
+		return nil, fmt.Errorf("invalid log_format %q (from %s): want %s or %s", s.LogFormat, sources["log_format"], LogFormatText, LogFormatJSON)
+	}
+	switch s.Tracing {
+	case "", TracingOTLP, TracingStdout:
+	default:
+		return nil, fmt.Errorf("invalid tracing %q (from %s): want %s or %s", s.Tracing, sources["tracing"], TracingOTLP, TracingStdout)
+	}
+	bools := []struct {
+		key string
+		dst *bool
+	}{
+		{"trial", &s.Trial},
+		{"mtls", &s.MTLS},
+		{"system_roots", &s.SystemRoots},
+	}
+	for _, b := range bools {
+		v := values[b.key]
+		if v == "" {
+			continue
+		}
+		parsed, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q (from %s)", b.key, v, sources[b.key])
+		}
+		*b.dst = parsed
+	}
+	durations := []struct {
+		key      string
+		dst      *time.Duration
+		positive bool
+	}{
+		{"grace_period", &s.GracePeriod, false},
+		{"poll_interval", &s.PollInterval, true},
+		{"max_wait", &s.MaxWait, false},
+		{"backoff_max", &s.BackoffMax, false},
+	}
+	for _, d := range durations {
+		v := values[d.key]
+		if v == "" {
+			continue
+		}
+		parsed, err := parseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q (from %s): %w", d.key, v, sources[d.key], err)
+		}
+		if d.positive && parsed <= 0 {
+			return nil, fmt.Errorf("invalid %s %q (from %s): must be positive", d.key, v, sources[d.key])
+		}
+		*d.dst = parsed
+	}
+	for _, pin := range strings.Split(values["pin_sha256"], ",") {
+		if pin = strings.TrimSpace(pin); pin != "" {
+			s.PinSHA256 = append(s.PinSHA256, pin)
+		}
+	}
+	metadata, err := parseMetadata(values["metadata"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid metadata (from %s): %w", sources["metadata"], err)
+	}
+	s.Metadata = metadata
+	if s.StateDir == "" {
+		dir, err := StateDir()
+		if err != nil {
+			return nil, err
+		}
+		s.StateDir = dir
+	}
+	// Ключ, сгенерированный ранее, используется, если его не задали явно
+	if s.LicenseKey == "" {
+		key, err := s.StoredKey()
+		if err != nil {
+			return nil, err
+		}
+		if key != "" {
+			s.LicenseKey = key
+			s.Sources["license_key"] = s.Path(keyFile)
+		}
+	}
+	return s, nil
+}
+
+// Path возвращает путь к файлу name в каталоге состояния
+func (s *Settings) Path(name string) string {
+	return filepath.Join(s.StateDir, name)
+}
+
+// UserConfigDir — $XDG_CONFIG_HOME/licence-approval (или аналог для ОС)
+func UserConfigDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, appName), nil
+}
+
+// StateDir — каталог состояния пользователя: $XDG_STATE_HOME/licence-approval,
+// по умолчанию ~/.local/state/licence-approval; на других ОС — каталог настроек
+func StateDir() (string, error) {
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		return filepath.Join(dir, appName), nil
+	}
+	if home, err := os.UserHomeDir(); err == nil && isXDGPlatform() {
+		return filepath.Join(home, ".local", "state", appName), nil
+	}
+	return UserConfigDir()
+}
+
+func findConfig(dir string) string {
+	for _, name := range configNames {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// parseDuration разбирает длительность; "unlimited" и "infinite" означают 0
+func parseDuration(v string) (time.Duration, error) {
+	switch strings.ToLower(v) {
+	case "unlimited", "infinite", "forever":
+		return 0, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, err
+	}
+	if d < 0 {
+		return 0, errors.New("must not be negative")
+	}
+	return d, nil
+}
+
+// parseMetadata разбирает пары "ключ=значение" через запятую
+func parseMetadata(v string) (map[string]string, error) {
+	if strings.TrimSpace(v) == "" {
+		return nil, nil
+	}
+	m := make(map[string]string)
+	for _, pair := range strings.Split(v, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		k, val, ok := strings.Cut(pair, "=")
+		k = strings.TrimSpace(k)
+		if !ok || k == "" {
+			return nil, fmt.Errorf("%q is not a key=value pair", pair)
+		}
+		m[k] = strings.TrimSpace(val)
+	}
+	return m, nil
+}
+
+func flagName(key string) string {
+	for _, s := range Known {
+		if s.Key == key {
+			return s.Flag
+		}
+	}
+	return key
+}
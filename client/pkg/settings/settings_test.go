@@ -0,0 +1,135 @@
+package settings
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// isolate убирает влияние окружения и каталогов пользователя на Load
+func isolate(t *testing.T) (userDir, systemDir, exeDir string) {
+	t.Helper()
+	for _, s := range Known {
+		t.Setenv(s.Env, "")
+	}
+	t.Setenv("LICENSE_CONFIG", "")
+	root := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(root, "config"))
+	t.Setenv("XDG_STATE_HOME", filepath.Join(root, "state"))
+
+	userDir = filepath.Join(root, "config", appName)
+	systemDir = filepath.Join(root, "etc")
+	exeDir = filepath.Join(root, "bin")
+	for _, dir := range []string{userDir, systemDir, exeDir} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	prev := SystemConfigDir
+	SystemConfigDir = systemDir
+	t.Cleanup(func() { SystemConfigDir = prev })
+	return userDir, systemDir, exeDir
+}
+
+func writeFile(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	userDir, systemDir, exeDir := isolate(t)
+
+	// Каждый слой задаёт свой набор параметров; параметр берётся из слоя
+	// с наибольшим приоритетом, где он задан
+	writeFile(t, filepath.Join(exeDir, "config.json"),
+		`{"server_url": "https://legacy", "product": "legacy", "organization": "legacy", "requester_name": "legacy", "app_version": "legacy", "old_key": "ignored"}`)
+	writeFile(t, filepath.Join(systemDir, "config.yaml"),
+		"server_url: https://system\nproduct: system\norganization: system\nrequester_name: system\n")
+	writeFile(t, filepath.Join(userDir, "config.yml"),
+		"LICENSE_SERVER_URL: https://user\nproduct: user\norganization: user\n")
+	t.Setenv("LICENSE_SERVER_URL", "https://env")
+	t.Setenv("LICENSE_PRODUCT", "env")
+
+	s, err := Load(Options{
+		Flags:    map[string]string{"server_url": "https://flag", "invite_code": ""},
+		ExeDir:   exeDir,
+		Defaults: map[string]string{"poll_interval": "30s", "app_version": "default", "invite_code": "default"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		key, got, want, source string
+	}{
+		{"server_url", s.ServerURL, "https://flag", "--server-url"},
+		{"product", s.Product, "env", "$LICENSE_PRODUCT"},
+		{"organization", s.Organization, "user", filepath.Join(userDir, "config.yml")},
+		{"requester_name", s.RequesterName, "system", filepath.Join(systemDir, "config.yaml")},
+		{"app_version", s.AppVersion, "legacy", filepath.Join(exeDir, "config.json")},
+		// Пустой флаг не перекрывает значение из нижних слоёв
+		{"invite_code", s.InviteCode, "default", "default"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.key, tt.got, tt.want)
+		}
+		if src := s.Sources[tt.key]; src != tt.source {
+			t.Errorf("%s source = %q, want %q", tt.key, src, tt.source)
+		}
+	}
+	if s.PollInterval != 30*time.Second {
+		t.Errorf("PollInterval = %s, want 30s", s.PollInterval)
+	}
+}
+
+func TestLoadConfigFileReplacesUserFile(t *testing.T) {
+	userDir, _, _ := isolate(t)
+	writeFile(t, filepath.Join(userDir, "config.yaml"), "product: user\norganization: user\n")
+	explicit := filepath.Join(t.TempDir(), "custom.json")
+	writeFile(t, explicit, `{"product": "custom"}`)
+
+	s, err := Load(Options{ConfigFile: explicit})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Product != "custom" {
+		t.Errorf("Product = %q, want %q", s.Product, "custom")
+	}
+	// Файл в XDG-каталоге при явном --config не читается вовсе
+	if s.Organization != "" {
+		t.Errorf("Organization = %q, want it unset", s.Organization)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		file  string
+		env   map[string]string
+		flags map[string]string
+	}{
+		{name: "unknown key in user file", file: "product: x\ncolour: blue\n"},
+		{name: "invalid duration", env: map[string]string{"LICENSE_GRACE_PERIOD": "soon"}},
+		{name: "non-positive poll interval", flags: map[string]string{"poll_interval": "0s"}},
+		{name: "invalid bool", env: map[string]string{"LICENSE_TRIAL": "maybe"}},
+		{name: "invalid log format", flags: map[string]string{"log_format": "xml"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userDir, _, _ := isolate(t)
+			if tt.file != "" {
+				writeFile(t, filepath.Join(userDir, "config.yaml"), tt.file)
+			}
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			if _, err := Load(Options{Flags: tt.flags}); err == nil {
+				t.Error("Load() succeeded, want error")
+			}
+		})
+	}
+}
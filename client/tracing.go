@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"example.com/licence-approval/client/pkg/settings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// tracingFlushTimeout ограничивает отправку спанов при завершении команды:
+// недоступный коллектор не должен задерживать выход
+const tracingFlushTimeout = 5 * time.Second
+
+// startTracing настраивает экспорт спанов OpenTelemetry по параметру tracing.
+// Пропагатор W3C ставится всегда, чтобы сервер продолжал трассировку клиента.
+func (c *cli) startTracing(exporter string) error {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exp sdktrace.SpanExporter
+	var err error
+	switch exporter {
+	case "":
+		return nil
+	case settings.TracingOTLP:
+		exp, err = otlptracehttp.New(context.Background())
+	case settings.TracingStdout:
+		// stdout занят результатом команды (--json), спаны — в stderr
+		exp, err = stdouttrace.New(stdouttrace.WithWriter(os.Stderr))
+	}
+	if err != nil {
+		return fmt.Errorf("create %s span exporter: %w", exporter, err)
+	}
+
+	res, err := resource.New(context.Background(),
+		resource.WithAttributes(semconv.ServiceName("license-client")),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+	)
+	if err != nil {
+		return fmt.Errorf("tracing resource: %w", err)
+	}
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exp), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	c.tracing = provider
+	return nil
+}
+
+// stopTracing отправляет накопленные спаны
+func (c *cli) stopTracing() {
+	if c.tracing == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), tracingFlushTimeout)
+	defer cancel()
+	if err := c.tracing.Shutdown(ctx); err != nil {
+		slog.Warn("Failed to export traces", "error", err)
+	}
+}
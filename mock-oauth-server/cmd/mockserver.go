@@ -1,23 +1,34 @@
 package main
 
 import (
-	"log"
+	"log/slog"
 	"mock-oauth-server/config"
 	"mock-oauth-server/internal/app"
+	"mock-oauth-server/internal/logging"
+	"os"
 )
 
 func main() {
+	if err := logging.Configure(); err != nil {
+		fatal("Failed to configure logging", err)
+	}
+
 	cfg, err := config.LoadConfig()
 	if err != nil {
-		log.Fatalf("Failed to load config: %v", err)
+		fatal("Failed to load config", err)
 	}
 
 	a, err := app.New(cfg)
 	if err != nil {
-		log.Fatalf("Failed to create App: %v", err)
+		fatal("Failed to create App", err)
 	}
 
 	if err := a.Run(); err != nil {
-		log.Fatalf("Failed to run server: %v", err)
+		fatal("Failed to run server", err)
 	}
 }
+
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
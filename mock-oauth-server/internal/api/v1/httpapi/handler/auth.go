@@ -2,7 +2,9 @@ package handler
 
 import (
 	"fmt"
-	"log"
+	"html"
+	"log/slog"
+	"mock-oauth-server/internal/logging"
 	"mock-oauth-server/internal/repository/inmem"
 	"net/http"
 	"net/url"
@@ -27,6 +29,9 @@ func (h *Handler) handleAuthorizeGet(w http.ResponseWriter, r *http.Request) {
 	redirectURI := q.Get("redirect_uri")
 	state := q.Get("state")
 	scope := q.Get("scope")
+	nonce := q.Get("nonce")
+	codeChallenge := q.Get("code_challenge")
+	codeChallengeMethod := q.Get("code_challenge_method")
 
 	if responseType != "code" {
 		http.Error(w, "unsupported response_type", http.StatusBadRequest)
@@ -36,9 +41,13 @@ func (h *Handler) handleAuthorizeGet(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "missing client_id or redirect_uri", http.StatusBadRequest)
 		return
 	}
+	if codeChallenge != "" && codeChallengeMethod != "S256" && codeChallengeMethod != "plain" {
+		http.Error(w, "unsupported code_challenge_method", http.StatusBadRequest)
+		return
+	}
 
 	// Примитивная форма
-	html := fmt.Sprintf(`
+	page := fmt.Sprintf(`
 <html>
  <body>
    <h3>Mock /authorize</h3>
@@ -48,16 +57,21 @@ func (h *Handler) handleAuthorizeGet(w http.ResponseWriter, r *http.Request) {
      <input type="hidden" name="redirect_uri" value="%s">
      <input type="hidden" name="state" value="%s">
      <input type="hidden" name="scope" value="%s">
+     <input type="hidden" name="nonce" value="%s">
+     <input type="hidden" name="code_challenge" value="%s">
+     <input type="hidden" name="code_challenge_method" value="%s">
      <label>Username:</label><input type="text" name="username"><br/>
      <label>Password:</label><input type="password" name="password"><br/>
      <input type="submit" value="Authorize">
    </form>
  </body>
 </html>
-`, responseType, clientID, redirectURI, state, scope)
+`, html.EscapeString(responseType), html.EscapeString(clientID), html.EscapeString(redirectURI),
+		html.EscapeString(state), html.EscapeString(scope), html.EscapeString(nonce),
+		html.EscapeString(codeChallenge), html.EscapeString(codeChallengeMethod))
 
 	w.Header().Set("Content-Type", "text/html")
-	w.Write([]byte(html))
+	w.Write([]byte(page))
 }
 
 func (h *Handler) handleAuthorizePost(w http.ResponseWriter, r *http.Request) {
@@ -70,6 +84,13 @@ func (h *Handler) handleAuthorizePost(w http.ResponseWriter, r *http.Request) {
 	clientID := r.FormValue("client_id")
 	redirectURI := r.FormValue("redirect_uri")
 	state := r.FormValue("state")
+	scope := r.FormValue("scope")
+	nonce := r.FormValue("nonce")
+	codeChallenge := r.FormValue("code_challenge")
+	codeChallengeMethod := r.FormValue("code_challenge_method")
+	if codeChallenge != "" && codeChallengeMethod == "" {
+		codeChallengeMethod = "plain"
+	}
 
 	username := r.FormValue("username")
 	password := r.FormValue("password")
@@ -90,7 +111,12 @@ func (h *Handler) handleAuthorizePost(w http.ResponseWriter, r *http.Request) {
 		ClientID:    clientID,
 		UserID:      "user-1",
 		RedirectURI: redirectURI,
-		Expiry:      time.Now().Add(5 * time.Minute),
+		Scope:       scope,
+		Nonce:       nonce,
+
+		CodeChallenge:       codeChallenge,
+		CodeChallengeMethod: codeChallengeMethod,
+		Expiry:              time.Now().Add(5 * time.Minute),
 	}
 
 	u, err := url.Parse(redirectURI)
@@ -104,6 +130,11 @@ func (h *Handler) handleAuthorizePost(w http.ResponseWriter, r *http.Request) {
 		q.Set("state", state)
 	}
 	u.RawQuery = q.Encode()
-	log.Printf("[AuthorizePost] code=%s -> redirect to %s\n", code, u.String())
+	// Код в журнале маскируется; redirect_uri — исходный, без code и state
+	slog.InfoContext(r.Context(), "Authorization code issued",
+		"request_id", logging.RequestID(r),
+		"client_id", clientID,
+		"code", code,
+		"redirect_uri", redirectURI)
 	http.Redirect(w, r, u.String(), http.StatusFound)
 }
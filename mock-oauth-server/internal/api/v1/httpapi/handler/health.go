@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"time"
+)
+
+// certMinValidity — сертификат, истекающий раньше, считается неготовым
+const certMinValidity = 7 * 24 * time.Hour
+
+// Version — версия сборки (-ldflags "-X mock-oauth-server/internal/api/v1/httpapi/handler.Version=...")
+var Version = "dev"
+
+// HealthHandler — пробы оркестратора и сведения о сборке
+type HealthHandler struct {
+	certFile string
+	keyFile  string
+}
+
+func NewHealthHandler(certFile, keyFile string) *HealthHandler {
+	return &HealthHandler{certFile: certFile, keyFile: keyFile}
+}
+
+// Healthz — процесс жив
+func (h *HealthHandler) Healthz(w http.ResponseWriter, r *http.Request) {
+	writeHealthJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// Readyz — сертификат TLS читается, подходит к ключу и не истекает в ближайшую неделю.
+// Причина отказа пишется только в журнал: /readyz доступен без аутентификации.
+func (h *HealthHandler) Readyz(w http.ResponseWriter, r *http.Request) {
+	if err := h.checkCertificate(time.Now()); err != nil {
+		slog.WarnContext(r.Context(), "Readiness check failed", "check", "tls_certificate", "error", err)
+		writeHealthJSON(w, http.StatusServiceUnavailable, map[string]interface{}{
+			"status": "not_ready",
+			"checks": map[string]interface{}{
+				"tls_certificate": map[string]string{"status": "fail"},
+			},
+		})
+		return
+	}
+	writeHealthJSON(w, http.StatusOK, map[string]interface{}{
+		"status": "ready",
+		"checks": map[string]interface{}{
+			"tls_certificate": map[string]string{"status": "ok"},
+		},
+	})
+}
+
+func (h *HealthHandler) checkCertificate(now time.Time) error {
+	pair, err := tls.LoadX509KeyPair(h.certFile, h.keyFile)
+	if err != nil {
+		return err
+	}
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return err
+	}
+	switch {
+	case now.Before(cert.NotBefore):
+		return fmt.Errorf("certificate is not valid before %s", cert.NotBefore.Format(time.RFC3339))
+	case now.After(cert.NotAfter):
+		return fmt.Errorf("certificate expired at %s", cert.NotAfter.Format(time.RFC3339))
+	case now.Add(certMinValidity).After(cert.NotAfter):
+		return fmt.Errorf("certificate expires soon, at %s", cert.NotAfter.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// Version — версия, ревизия и время сборки
+func (h *HealthHandler) Version(w http.ResponseWriter, r *http.Request) {
+	info := map[string]interface{}{"version": Version, "go_version": runtime.Version()}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				info["revision"] = s.Value
+			case "vcs.time":
+				info["build_time"] = s.Value
+			case "vcs.modified":
+				info["modified"] = s.Value == "true"
+			}
+		}
+	}
+	writeHealthJSON(w, http.StatusOK, info)
+}
+
+func writeHealthJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -1,11 +1,17 @@
 package handler
 
 import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
 	"encoding/json"
 	"mime"
 	"mock-oauth-server/internal/repository/inmem"
 	"net/http"
+	"strings"
 	"time"
+
+	"github.com/golang-jwt/jwt"
 )
 
 // TokenHandler обрабатывает /oauth/token.
@@ -69,9 +75,14 @@ func (h *Handler) handleAuthorizationCodeGrant(w http.ResponseWriter, r *http.Re
 		http.Error(w, "code expired", http.StatusBadRequest)
 		return
 	}
-	// Удаляем использованный code
+	// Удаляем использованный code (в том числе при неверном verifier — код одноразовый)
 	delete(h.store.AuthorizationCodes, codeVal)
 
+	if !verifyCodeVerifier(ac, r.FormValue("code_verifier")) {
+		http.Error(w, "invalid code_verifier", http.StatusBadRequest)
+		return
+	}
+
 	// Генерим access_token и refresh_token
 	accessToken := h.store.GenerateRandomString(32)
 	refreshToken := h.store.GenerateRandomString(32)
@@ -96,10 +107,65 @@ func (h *Handler) handleAuthorizationCodeGrant(w http.ResponseWriter, r *http.Re
 		"expires_in":    60,
 		"refresh_token": refreshToken,
 	}
+	if hasScope(ac.Scope, "openid") {
+		idToken, err := h.issueIDToken(r, ac)
+		if err != nil {
+			http.Error(w, "cannot issue id_token", http.StatusInternalServerError)
+			return
+		}
+		resp["id_token"] = idToken
+	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(resp)
 }
 
+// verifyCodeVerifier проверяет PKCE code_verifier против сохранённого challenge.
+// Если клиент не присылал challenge, verifier не требуется.
+func verifyCodeVerifier(ac *inmem.AuthorizationCode, verifier string) bool {
+	if ac.CodeChallenge == "" {
+		return true
+	}
+	if verifier == "" {
+		return false
+	}
+	expected := verifier
+	if ac.CodeChallengeMethod == "S256" {
+		sum := sha256.Sum256([]byte(verifier))
+		expected = base64.RawURLEncoding.EncodeToString(sum[:])
+	}
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(ac.CodeChallenge)) == 1
+}
+
+// issueIDToken выпускает id_token (HS256) с nonce из запроса /authorize
+func (h *Handler) issueIDToken(r *http.Request, ac *inmem.AuthorizationCode) (string, error) {
+	username := ac.UserID
+	if u, ok := h.store.Users[ac.UserID]; ok {
+		username = u.Username
+	}
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":                "https://" + r.Host,
+		"sub":                ac.UserID,
+		"aud":                ac.ClientID,
+		"iat":                now.Unix(),
+		"exp":                now.Add(5 * time.Minute).Unix(),
+		"preferred_username": username,
+	}
+	if ac.Nonce != "" {
+		claims["nonce"] = ac.Nonce
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(h.store.IDTokenKey)
+}
+
+func hasScope(scope, want string) bool {
+	for _, s := range strings.Fields(scope) {
+		if s == want {
+			return true
+		}
+	}
+	return false
+}
+
 func (h *Handler) handleRefreshTokenGrant(w http.ResponseWriter, r *http.Request) {
 	refreshVal := r.FormValue("refresh_token")
 	clientID := r.FormValue("client_id")
@@ -2,16 +2,26 @@ package router
 
 import (
 	"mock-oauth-server/internal/api/v1/httpapi/handler"
+	"mock-oauth-server/internal/logging"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
 )
 
-// New создаёт chi.Router и регистрирует все эндпоинты
-func New() http.Handler {
+// New создаёт chi.Router и регистрирует все эндпоинты; certFile и keyFile
+// нужны пробе готовности
+func New(certFile, keyFile string) http.Handler {
 	r := chi.NewRouter()
+	r.Use(middleware.RequestID, logging.Middleware)
 
 	h := handler.NewHandler()
+	health := handler.NewHealthHandler(certFile, keyFile)
+
+	// Пробы оркестратора и сведения о сборке
+	r.Get("/healthz", health.Healthz)
+	r.Get("/readyz", health.Readyz)
+	r.Get("/version", health.Version)
 
 	r.Get("/api/v1/config/groups", h.GetGroups)
 	r.Post("/api/v1/config/group", h.CreateGroup)
@@ -3,7 +3,7 @@ package app
 import (
 	"crypto/tls"
 	"fmt"
-	"log"
+	"log/slog"
 	"mock-oauth-server/config"
 	"mock-oauth-server/internal/api/v1/httpapi/router"
 	"net/http"
@@ -19,7 +19,7 @@ type App struct {
 }
 
 func New(cfg *config.Config) (*App, error) {
-	r := router.New()
+	r := router.New(cfg.CertFile, cfg.KeyFile)
 
 	if _, err := os.Stat(cfg.CertFile); err != nil {
 		return nil, fmt.Errorf("cert file not found: %v", err)
@@ -50,19 +50,19 @@ func (a *App) Run() error {
 
 	go func() {
 		<-quit
-		log.Println("[App] Shutting down mock-oauth-server gracefully...")
+		slog.Info("Shutting down mock-oauth-server gracefully")
 		if err := a.server.Close(); err != nil {
-			log.Printf("[App] Server Close error: %v\n", err)
+			slog.Error("Server close error", "error", err)
 		}
 	}()
 
-	log.Printf("[App] Starting mock OAuth2.0 server on %s...\n", a.cfg.Addr)
+	slog.Info("Starting mock OAuth2.0 server", "addr", a.cfg.Addr)
 
 	err := a.server.ListenAndServeTLS(a.cfg.CertFile, a.cfg.KeyFile)
 	if err != nil && err != http.ErrServerClosed {
 		return fmt.Errorf("ListenAndServeTLS error: %w", err)
 	}
 	time.Sleep(1 * time.Second)
-	log.Println("[App] Server stopped.")
+	slog.Info("Server stopped")
 	return nil
 }
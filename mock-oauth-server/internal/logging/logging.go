@@ -0,0 +1,100 @@
+// Package logging — структурированные логи mock-oauth-server (log/slog):
+// формат и уровень из LOG_FORMAT и LOG_LEVEL, X-Request-ID, журнал запросов
+// и маскирование кодов авторизации и токенов.
+package logging
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// RequestIDHeader — идентификатор запроса, общий с сервером лицензий
+const RequestIDHeader = "X-Request-ID"
+
+// secretKeys — атрибуты, значения которых в логах маскируются
+var secretKeys = map[string]bool{
+	"code":          true,
+	"token":         true,
+	"access_token":  true,
+	"id_token":      true,
+	"client_secret": true,
+	"code_verifier": true,
+	"password":      true,
+}
+
+// Configure настраивает логгер по умолчанию по LOG_FORMAT (text, json) и LOG_LEVEL
+func Configure() error {
+	level := slog.LevelInfo
+	if v := os.Getenv("LOG_LEVEL"); v != "" {
+		if err := level.UnmarshalText([]byte(v)); err != nil {
+			return fmt.Errorf("invalid LOG_LEVEL %q", v)
+		}
+	}
+	opts := &slog.HandlerOptions{Level: level, ReplaceAttr: redactAttr}
+	switch format := os.Getenv("LOG_FORMAT"); format {
+	case "", "text":
+		slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, opts)))
+	case "json":
+		slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stderr, opts)))
+	default:
+		return fmt.Errorf("invalid LOG_FORMAT %q (want text or json)", format)
+	}
+	return nil
+}
+
+// Redact оставляет от секрета только первые символы
+func Redact(s string) string {
+	if len(s) < 16 {
+		return "[redacted]"
+	}
+	return s[:4] + "…[redacted]"
+}
+
+func redactAttr(_ []string, a slog.Attr) slog.Attr {
+	if secretKeys[a.Key] && a.Value.Kind() == slog.KindString {
+		return slog.String(a.Key, Redact(a.Value.String()))
+	}
+	return a
+}
+
+// Middleware возвращает X-Request-ID в ответе и пишет журнал запросов; идентификатор
+// берётся из запроса или создаётся (chi middleware.RequestID должен стоять раньше)
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := middleware.GetReqID(r.Context())
+		w.Header().Set(RequestIDHeader, id)
+
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		start := time.Now()
+		next.ServeHTTP(ww, r)
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		// Успешные пробы оркестратора — только на уровне debug
+		level := slog.LevelInfo
+		if (r.URL.Path == "/healthz" || r.URL.Path == "/readyz") && status < http.StatusInternalServerError {
+			level = slog.LevelDebug
+		}
+		// Путь без параметров: в query бывают code и state
+		slog.Log(r.Context(), level, "HTTP request",
+			"request_id", id,
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", status,
+			"bytes", ww.BytesWritten(),
+			"duration_ms", time.Since(start).Milliseconds(),
+		)
+	})
+}
+
+// RequestID — идентификатор текущего запроса для записей обработчиков
+func RequestID(r *http.Request) string {
+	return middleware.GetReqID(r.Context())
+}
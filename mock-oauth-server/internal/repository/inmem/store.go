@@ -22,7 +22,12 @@ type AuthorizationCode struct {
 	ClientID    string
 	UserID      string
 	RedirectURI string
-	Expiry      time.Time
+	Scope       string
+	Nonce       string
+	// PKCE (RFC 7636): challenge и метод, присланные в /authorize
+	CodeChallenge       string
+	CodeChallengeMethod string
+	Expiry              time.Time
 }
 
 type AccessToken struct {
@@ -48,6 +53,9 @@ type Store struct {
 	AuthorizationCodes map[string]*AuthorizationCode
 	AccessTokens       map[string]*AccessToken
 	RefreshTokens      map[string]*RefreshToken
+
+	// Ключ для подписи id_token (HS256), генерируется при старте
+	IDTokenKey []byte
 }
 
 func NewStore() *Store {
@@ -57,7 +65,9 @@ func NewStore() *Store {
 		AuthorizationCodes: make(map[string]*AuthorizationCode),
 		AccessTokens:       make(map[string]*AccessToken),
 		RefreshTokens:      make(map[string]*RefreshToken),
+		IDTokenKey:         make([]byte, 32),
 	}
+	rand.Read(s.IDTokenKey)
 	// дефолт user-1
 	s.Users["user-1"] = &User{
 		ID:       "user-1",
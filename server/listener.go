@@ -0,0 +1,105 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"example.com/licence-approval/server/pkg/clientcert"
+
+	"github.com/spf13/viper"
+)
+
+// listenerDefaults — адрес и таймауты HTTPS-листенера. WRITE_TIMEOUT покрывает
+// и самые долгие ответы (выгрузка файла офлайн-активации).
+func listenerDefaults() {
+	viper.SetDefault("LISTEN_ADDR", ":8443")
+	viper.SetDefault("READ_HEADER_TIMEOUT", "10s")
+	viper.SetDefault("READ_TIMEOUT", "30s")
+	viper.SetDefault("WRITE_TIMEOUT", "60s")
+	viper.SetDefault("IDLE_TIMEOUT", "120s")
+	viper.SetDefault("SHUTDOWN_TIMEOUT", "30s")
+	viper.SetDefault("TLS_MIN_VERSION", "1.2")
+}
+
+// newServer собирает http.Server по LISTEN_ADDR, *_TIMEOUT, TLS_MIN_VERSION и
+// TLS_CIPHER_SUITES (имена через запятую, как в crypto/tls; пусто — набор Go
+// по умолчанию). Наборы шифров задаются только для TLS 1.2: в TLS 1.3 их не выбрать.
+func newServer(handler http.Handler) (*http.Server, error) {
+	minVersion, err := parseTLSVersion(viper.GetString("TLS_MIN_VERSION"))
+	if err != nil {
+		return nil, err
+	}
+	suites, err := parseCipherSuites(viper.GetString("TLS_CIPHER_SUITES"))
+	if err != nil {
+		return nil, err
+	}
+
+	// Настройки взаимного TLS (если он включён) дополняются общими
+	tlsConfig := clientcert.TLSConfig()
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{}
+	}
+	tlsConfig.MinVersion = minVersion
+	tlsConfig.CipherSuites = suites
+
+	return &http.Server{
+		Addr:              viper.GetString("LISTEN_ADDR"),
+		Handler:           handler,
+		TLSConfig:         tlsConfig,
+		ReadHeaderTimeout: viper.GetDuration("READ_HEADER_TIMEOUT"),
+		ReadTimeout:       viper.GetDuration("READ_TIMEOUT"),
+		WriteTimeout:      viper.GetDuration("WRITE_TIMEOUT"),
+		IdleTimeout:       viper.GetDuration("IDLE_TIMEOUT"),
+	}, nil
+}
+
+// parseTLSVersion — "1.2" или "1.3"; более старые версии не поддерживаются
+func parseTLSVersion(v string) (uint16, error) {
+	switch strings.TrimSpace(v) {
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	}
+	return 0, fmt.Errorf("unsupported TLS_MIN_VERSION %q (1.2 or 1.3)", v)
+}
+
+// parseCipherSuites разбирает список имён наборов шифров TLS 1.2. Небезопасные
+// наборы (tls.InsecureCipherSuites) не принимаются, как и наборы TLS 1.3:
+// crypto/tls их не настраивает, и такой список молча ничего бы не менял.
+func parseCipherSuites(list string) ([]uint16, error) {
+	if strings.TrimSpace(list) == "" {
+		return nil, nil
+	}
+	known := make(map[string]uint16)
+	for _, s := range tls.CipherSuites() {
+		if supportsTLS12(s) {
+			known[s.Name] = s.ID
+		}
+	}
+
+	var ids []uint16
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		id, ok := known[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown, insecure or TLS 1.3 cipher suite %q in TLS_CIPHER_SUITES", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func supportsTLS12(s *tls.CipherSuite) bool {
+	for _, v := range s.SupportedVersions {
+		if v == tls.VersionTLS12 {
+			return true
+		}
+	}
+	return false
+}
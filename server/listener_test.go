@@ -0,0 +1,57 @@
+package main
+
+import (
+	"crypto/tls"
+	"reflect"
+	"testing"
+)
+
+func TestParseCipherSuites(t *testing.T) {
+	tests := []struct {
+		name    string
+		list    string
+		want    []uint16
+		wantErr bool
+	}{
+		{name: "empty means Go defaults", list: " ", want: nil},
+		{
+			name: "TLS 1.2 suites with spaces",
+			list: "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256, TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,",
+			want: []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256, tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256},
+		},
+		{name: "TLS 1.3 suite", list: "TLS_AES_128_GCM_SHA256", wantErr: true},
+		{name: "TLS 1.3 suite among TLS 1.2 ones", list: "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,TLS_CHACHA20_POLY1305_SHA256", wantErr: true},
+		{name: "insecure suite", list: "TLS_RSA_WITH_RC4_128_SHA", wantErr: true},
+		{name: "unknown suite", list: "TLS_NOPE", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseCipherSuites(tt.list)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseCipherSuites(%q) error = %v, wantErr %v", tt.list, err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseCipherSuites(%q) = %v, want %v", tt.list, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTLSVersion(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    uint16
+		wantErr bool
+	}{
+		{in: "1.2", want: tls.VersionTLS12},
+		{in: " 1.3 ", want: tls.VersionTLS13},
+		{in: "1.1", wantErr: true},
+		{in: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseTLSVersion(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseTLSVersion(%q) = %v, %v; want %v, wantErr %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
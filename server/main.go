@@ -3,73 +3,245 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"syscall"
+	"time"
 
 	"example.com/licence-approval/server/config"
-	"example.com/licence-approval/server/pkg/auth"
+	"example.com/licence-approval/server/pkg/adminauth"
+	"example.com/licence-approval/server/pkg/clientcert"
 	"example.com/licence-approval/server/pkg/db"
+	"example.com/licence-approval/server/pkg/health"
+	"example.com/licence-approval/server/pkg/i18n"
+	"example.com/licence-approval/server/pkg/licensing"
+	"example.com/licence-approval/server/pkg/logging"
+	"example.com/licence-approval/server/pkg/metrics"
+	"example.com/licence-approval/server/pkg/orgs"
 	"example.com/licence-approval/server/pkg/security"
+	"example.com/licence-approval/server/pkg/tracing"
+	"example.com/licence-approval/server/templates"
 
 	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 	"github.com/spf13/viper"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gorilla/mux/otelmux"
 )
 
 func main() {
 	cfg, err := loadConfigSameDirAsBinary()
 	if err != nil {
-		log.Fatalf("Error loading config: %v", err)
+		logging.Fatal("Error loading config", "error", err)
 	}
 
-	// Настраиваем OAuth2
-	auth.InitOAuthConfig(cfg)
-	auth.SetupSessionStore(cfg)
+	// Логи: LOG_FORMAT=text|json, LOG_LEVEL=debug|info|warn|error
+	viper.SetDefault("LOG_FORMAT", logging.FormatText)
+	viper.SetDefault("LOG_LEVEL", "info")
+	if err := logging.Configure(viper.GetString("LOG_FORMAT"), viper.GetString("LOG_LEVEL")); err != nil {
+		logging.Fatal("Error configuring logging", "error", err)
+	}
+
+	// Настраиваем OAuth2 (PKCE) и сессии администраторов
+	adminauth.Init(cfg)
 
 	// DB init
 	db.Init()
 	db.Migrate()
+	licensing.Migrate()
+	orgs.Migrate()
+	// Шаблоны админки разбираются один раз и общие для всех пакетов
+	adminTemplates := templates.ParseTemplates()
+	licensing.Init(cfg, adminTemplates)
+	orgs.Init(adminTemplates)
+
+	// Пробные лицензии: длительность и урезанный TAG
+	viper.SetDefault("TRIAL_DURATION", "336h")
+	viper.SetDefault("TRIAL_TAG", 1)
+	licensing.ConfigureTrials(viper.GetDuration("TRIAL_DURATION"), viper.GetInt("TRIAL_TAG"))
+
+	// Лимит переносов лицензии на другую машину
+	viper.SetDefault("TRANSFER_LIMIT", 2)
+	viper.SetDefault("TRANSFER_WINDOW", "720h")
+	licensing.ConfigureTransfers(viper.GetInt("TRANSFER_LIMIT"), viper.GetDuration("TRANSFER_WINDOW"))
+
+	// Правила автоматического одобрения/отклонения
+	if policyFile := viper.GetString("POLICY_FILE"); policyFile != "" {
+		if err := licensing.LoadPolicy(policyFile); err != nil {
+			logging.Fatal("Error loading policy", "file", policyFile, "error", err)
+		}
+		slog.Info("Policy loaded", "file", policyFile)
+	}
+
+	// Взаимный TLS: клиентские сертификаты выдаются при одобрении заявки
+	viper.SetDefault("MTLS_MODE", "off")
+	viper.SetDefault("CLIENT_CERT_VALIDITY", "8760h")
+	err = clientcert.Configure(clientcert.Mode(viper.GetString("MTLS_MODE")),
+		viper.GetString("CLIENT_CA_CERT"), viper.GetString("CLIENT_CA_KEY"),
+		viper.GetDuration("CLIENT_CERT_VALIDITY"))
+	if err != nil {
+		logging.Fatal("Error configuring mutual TLS", "error", err)
+	}
+	if clientcert.Enabled() {
+		slog.Info("Mutual TLS enabled", "mode", viper.GetString("MTLS_MODE"))
+	}
 
 	// Загрузка ключей (если нужно для лицензий)
 	err = security.LoadKeys(cfg.PrivateKeyPath, cfg.PublicKeyPath)
 	if err != nil {
-		log.Fatalf("Error loading security keys: %v", err)
+		logging.Fatal("Error loading security keys", "error", err)
+	}
+
+	// Проверки готовности для /readyz; сертификаты, истекающие раньше
+	// CERT_MIN_VALIDITY, считаются неготовыми — чтобы их успели заменить
+	viper.SetDefault("CERT_MIN_VALIDITY", "168h")
+	minValidity := viper.GetDuration("CERT_MIN_VALIDITY")
+	health.Register("database", db.DB.PingContext)
+	health.Register("signing_keys", licensing.CheckSigningKeys)
+	health.Register("tls_certificate", health.CertificateCheck(cfg.CertFile, cfg.KeyFile, minValidity))
+	if ca := clientcert.CA(); ca != nil {
+		health.Register("client_ca", func(ctx context.Context) error {
+			return health.CheckExpiry(ca, minValidity, time.Now())
+		})
+	}
+
+	// Метрики Prometheus — на отдельном адресе, чтобы не открывать их вместе с API;
+	// пустой METRICS_ADDR отключает листенер
+	viper.SetDefault("METRICS_ADDR", "127.0.0.1:9090")
+	var metricsServer *http.Server
+	if addr := viper.GetString("METRICS_ADDR"); addr != "" {
+		metrics.Registry.MustRegister(collectors.NewDBStatsCollector(db.DB, "licensing"), licensing.NewCollector())
+		metricsServer = metrics.NewServer(addr)
+		go func() {
+			if err := metricsServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logging.Fatal("Metrics listener error", "error", err)
+			}
+		}()
+		slog.Info("Metrics listener started", "addr", addr)
+	}
+
+	// Трассировка OpenTelemetry: TRACING_EXPORTER=otlp (адрес коллектора —
+	// OTEL_EXPORTER_OTLP_ENDPOINT) или stdout; пусто — спаны не экспортируются
+	shutdownTracing, err := tracing.Configure(viper.GetString("TRACING_EXPORTER"), "license-server")
+	if err != nil {
+		logging.Fatal("Error configuring tracing", "error", err)
+	}
+	if exporter := viper.GetString("TRACING_EXPORTER"); exporter != "" {
+		slog.Info("Tracing enabled", "exporter", exporter)
 	}
 
 	router := mux.NewRouter()
+	// Спан на каждый запрос; контекст трассировки клиента берётся из traceparent
+	router.Use(otelmux.Middleware("license-server"))
+	router.Use(metrics.Middleware)
+	// Ошибки маршрутизации /api/* — в формате problem+json, как и ошибки обработчиков
+	router.NotFoundHandler = metrics.Middleware(http.HandlerFunc(licensing.NotFoundHandler))
+	router.MethodNotAllowedHandler = metrics.Middleware(http.HandlerFunc(licensing.MethodNotAllowedHandler))
+
+	// Пробы оркестратора и сведения о сборке — без авторизации
+	router.HandleFunc("/healthz", health.HealthzHandler).Methods("GET")
+	router.HandleFunc("/readyz", health.ReadyzHandler).Methods("GET")
+	router.HandleFunc("/version", health.VersionHandler).Methods("GET")
 
 	// Роуты авторизации
-	router.HandleFunc("/auth/login", auth.LoginHandler).Methods("GET")
-	router.HandleFunc("/oauth-cb", auth.CallbackHandler).Methods("GET")
-	router.HandleFunc("/auth/logout", auth.LogoutHandler).Methods("GET")
+	router.HandleFunc("/auth/login", adminauth.LoginHandler).Methods("GET")
+	router.HandleFunc("/oauth-cb", adminauth.CallbackHandler).Methods("GET")
+	router.HandleFunc("/auth/logout", adminauth.LogoutHandler).Methods("GET")
+
+	// Bootstrap и стили админки встроены в бинарник
+	router.PathPrefix(templates.StaticPrefix).Handler(templates.StaticHandler()).Methods("GET", "HEAD")
 
 	// Админские маршруты
 	adminRouter := router.PathPrefix("/admin").Subrouter()
-	adminRouter.Use(auth.AuthMiddleware())
-	adminRouter.HandleFunc("/license-requests", db.GetLicenseRequestsHandler).Methods("GET")
-	adminRouter.HandleFunc("/approve-license", db.ApproveLicenseRequestHandler).Methods("POST")
-	adminRouter.HandleFunc("/reject-license", db.RejectLicenseRequestHandler).Methods("POST")
+	adminRouter.Use(adminauth.AuthMiddleware())
+	adminRouter.HandleFunc("/dashboard", licensing.DashboardHandler).Methods("GET")
+	adminRouter.HandleFunc("/license-requests", licensing.GetLicenseRequestsHandler).Methods("GET")
+	adminRouter.HandleFunc("/license-request", licensing.GetLicenseRequestHandler).Methods("GET")
+	adminRouter.HandleFunc("/approve-license", licensing.ApproveLicenseRequestHandler).Methods("POST")
+	adminRouter.HandleFunc("/reject-license", licensing.RejectLicenseRequestHandler).Methods("POST")
+	adminRouter.HandleFunc("/products", licensing.ProductsHandler).Methods("GET")
+	adminRouter.HandleFunc("/products", licensing.CreateProductHandler).Methods("POST")
+	adminRouter.HandleFunc("/products/versions", licensing.AddProductVersionHandler).Methods("POST")
+	adminRouter.HandleFunc("/transfer-license", licensing.TransferLicenseHandler).Methods("POST")
+	adminRouter.HandleFunc("/release-license", licensing.ReleaseLicenseHandler).Methods("POST")
+	adminRouter.HandleFunc("/assign-organization", licensing.AssignOrganizationHandler).Methods("POST")
+	adminRouter.HandleFunc("/policy", licensing.PolicyHandler).Methods("GET")
+	adminRouter.HandleFunc("/policy/dry-run", licensing.PolicyDryRunHandler).Methods("POST")
+	adminRouter.HandleFunc("/offline-activation", licensing.OfflineActivationHandler).Methods("GET")
+	adminRouter.HandleFunc("/offline-activation", licensing.OfflineActivationUploadHandler).Methods("POST")
+	adminRouter.HandleFunc("/license-response", licensing.LicenseResponseHandler).Methods("GET")
+	adminRouter.HandleFunc("/organizations", orgs.OrganizationsHandler).Methods("GET")
+	adminRouter.HandleFunc("/organizations", orgs.CreateOrganizationHandler).Methods("POST")
+	adminRouter.HandleFunc("/organizations/contacts", orgs.AddContactHandler).Methods("POST")
+	adminRouter.HandleFunc("/organizations/quota", orgs.SetQuotaHandler).Methods("POST")
+	adminRouter.HandleFunc("/language", i18n.LanguageHandler).Methods("GET")
 
-	// Открытые маршруты
-	router.HandleFunc("/api/check-license", db.CheckLicenseHandler).Methods("GET")
-	router.HandleFunc("/api/create-license-request", db.CreateLicenseRequestHandler).Methods("POST")
+	// Открытые маршруты (продукт задаётся параметром product или заголовком X-License-Product)
+	router.HandleFunc("/api/check-license", licensing.CheckLicenseHandler).Methods("GET")
+	router.HandleFunc("/api/create-license-request", licensing.CreateLicenseRequestHandler).Methods("POST")
+	router.HandleFunc("/api/deactivate", licensing.DeactivateLicenseHandler).Methods("POST")
+	router.HandleFunc("/api/public-key", licensing.ProductPublicKeyHandler).Methods("GET")
 
-	log.Println("Certificate:", cfg.CertFile)
-	log.Println("KeyFile:", cfg.KeyFile)
+	slog.Info("TLS certificate", "cert_file", cfg.CertFile, "key_file", cfg.KeyFile)
 	if _, err := os.Stat(cfg.CertFile); os.IsNotExist(err) {
-		log.Fatalf("No cert file %s", cfg.CertFile)
+		logging.Fatal("No cert file", "path", cfg.CertFile)
 	}
 	if _, err := os.Stat(cfg.KeyFile); os.IsNotExist(err) {
-		log.Fatalf("No key file %s", cfg.KeyFile)
+		logging.Fatal("No key file", "path", cfg.KeyFile)
 	}
 
-	log.Println("Server on :8443 ...")
-	err = http.ListenAndServeTLS(":8443", cfg.CertFile, cfg.KeyFile, router)
+	// Адрес, таймауты и параметры TLS листенера
+	listenerDefaults()
+	// Идентификатор и журнал запросов — для всех маршрутов, включая ошибки маршрутизации
+	server, err := newServer(logging.Middleware(router))
 	if err != nil {
-		log.Fatalf("ListenAndServeTLS error: %v", err)
+		logging.Fatal("Error configuring listener", "error", err)
+	}
+
+	// SIGINT/SIGTERM: новые соединения не принимаются, текущие запросы
+	// дорабатывают до SHUTDOWN_TIMEOUT, затем останавливается листенер метрик
+	// (его коллектор читает статистику пула) и закрывается пул БД
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		slog.Info("Server listening", "addr", server.Addr)
+		serveErr <- server.ListenAndServeTLS(cfg.CertFile, cfg.KeyFile)
+	}()
+
+	select {
+	case err := <-serveErr:
+		logging.Fatal("ListenAndServeTLS error", "error", err)
+	case <-ctx.Done():
+	}
+	stop()
+	slog.Info("Shutting down, draining in-flight requests", "timeout", viper.GetDuration("SHUTDOWN_TIMEOUT"))
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), viper.GetDuration("SHUTDOWN_TIMEOUT"))
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		slog.Error("Graceful shutdown failed, closing connections", "error", err)
+		server.Close()
+	}
+	if metricsServer != nil {
+		if err := metricsServer.Shutdown(shutdownCtx); err != nil {
+			slog.Warn("Metrics listener shutdown failed", "error", err)
+			metricsServer.Close()
+		}
+	}
+	if err := db.DB.Close(); err != nil {
+		slog.Error("Error closing database", "error", err)
+	}
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		slog.Warn("Failed to export traces", "error", err)
 	}
+	slog.Info("Server stopped")
 }
 
 // Загружает .env рядом с бинарником
@@ -84,7 +256,7 @@ func loadConfigSameDirAsBinary() (*config.Config, error) {
 	viper.SetConfigFile(envPath)
 	viper.SetConfigType("env")
 	if err := viper.ReadInConfig(); err != nil {
-		log.Printf("No .env in %s, using environment: %v\n", exeDir, err)
+		slog.Info("No .env next to the binary, using environment", "dir", exeDir, "error", err)
 	}
 	viper.AutomaticEnv()
 
@@ -0,0 +1,224 @@
+// Package adminauth реализует вход администраторов через OAuth2 (authorization code + PKCE)
+// с привязкой state/nonce к сессии до логина.
+package adminauth
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"example.com/licence-approval/server/config"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/sessions"
+	"golang.org/x/oauth2"
+)
+
+const (
+	sessionName      = "license-admin"
+	loginSessionName = "license-admin-login"
+
+	// Время жизни незавершённого логина (state/nonce/verifier)
+	loginTTL = 5 * time.Minute
+	// Время жизни сессии администратора
+	sessionTTL = 8 * time.Hour
+
+	defaultReturnTo = "/admin/license-requests"
+)
+
+var (
+	oauthConfig *oauth2.Config
+	store       *sessions.CookieStore
+	logins      = newPendingLogins()
+)
+
+// Init настраивает OAuth2 клиент и хранилище сессий
+func Init(cfg *config.Config) {
+	oauthConfig = &oauth2.Config{
+		ClientID:     cfg.OAuthClientID,
+		ClientSecret: cfg.OAuthClientSecret,
+		RedirectURL:  cfg.OAuthRedirectURL,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  cfg.OAuthAuthURL,
+			TokenURL: cfg.OAuthTokenURL,
+		},
+		Scopes: []string{"openid"},
+	}
+
+	store = sessions.NewCookieStore([]byte(cfg.SessionSecret))
+	store.Options = &sessions.Options{
+		Path:     "/",
+		MaxAge:   int(sessionTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// LoginHandler начинает вход: генерирует state, nonce и PKCE verifier,
+// запоминает их и перенаправляет на /authorize провайдера.
+func LoginHandler(w http.ResponseWriter, r *http.Request) {
+	state, err := randomToken()
+	if err != nil {
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+	nonce, err := randomToken()
+	if err != nil {
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	logins.put(state, &pendingLogin{
+		nonce:    nonce,
+		verifier: verifier,
+		returnTo: safeReturnTo(r.URL.Query().Get("next")),
+		expires:  time.Now().Add(loginTTL),
+	})
+
+	// state дополнительно привязываем к браузеру через отдельную короткую cookie
+	sess, _ := store.New(r, loginSessionName)
+	sess.Options = &sessions.Options{
+		Path:     "/",
+		MaxAge:   int(loginTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	}
+	sess.Values["state"] = state
+	if err := sess.Save(r, w); err != nil {
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+
+	authURL := oauthConfig.AuthCodeURL(state,
+		oauth2.S256ChallengeOption(verifier),
+		oauth2.SetAuthURLParam("nonce", nonce),
+	)
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// CallbackHandler завершает вход: проверяет state (однократно, с ограниченным сроком),
+// обменивает code на токен с code_verifier и сверяет nonce из id_token.
+func CallbackHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	loginSess, _ := store.Get(r, loginSessionName)
+	cookieState, _ := loginSess.Values["state"].(string)
+	// cookie логина одноразовая — удаляем при любом исходе
+	loginSess.Options.MaxAge = -1
+	_ = loginSess.Save(r, w)
+
+	state := q.Get("state")
+	if state == "" || state != cookieState {
+		http.Error(w, "Invalid state", http.StatusBadRequest)
+		return
+	}
+	pending, ok := logins.take(state)
+	if !ok {
+		http.Error(w, "Login expired or already used", http.StatusBadRequest)
+		return
+	}
+
+	if e := q.Get("error"); e != "" {
+		slog.WarnContext(r.Context(), "OAuth provider returned error", "oauth_error", e)
+		http.Error(w, "Authorization failed", http.StatusUnauthorized)
+		return
+	}
+	code := q.Get("code")
+	if code == "" {
+		http.Error(w, "Missing code", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+	token, err := oauthConfig.Exchange(ctx, code, oauth2.VerifierOption(pending.verifier))
+	if err != nil {
+		slog.WarnContext(r.Context(), "OAuth code exchange failed", "error", err)
+		http.Error(w, "Authorization failed", http.StatusUnauthorized)
+		return
+	}
+
+	// openid запрошен всегда: без id_token nonce не проверить, и вход отклоняется
+	rawIDToken, _ := token.Extra("id_token").(string)
+	if rawIDToken == "" {
+		slog.WarnContext(r.Context(), "Token response has no id_token")
+		http.Error(w, "Authorization failed", http.StatusUnauthorized)
+		return
+	}
+	claims, err := parseIDToken(rawIDToken)
+	if err != nil {
+		slog.WarnContext(r.Context(), "Invalid id_token", "error", err)
+		http.Error(w, "Authorization failed", http.StatusUnauthorized)
+		return
+	}
+	if err := claims.validate(oauthConfig.ClientID, pending.nonce, time.Now()); err != nil {
+		slog.WarnContext(r.Context(), "id_token validation failed", "error", err)
+		http.Error(w, "Authorization failed", http.StatusUnauthorized)
+		return
+	}
+	username := claims.username()
+
+	sess, _ := store.Get(r, sessionName)
+	sess.Values["authenticated"] = true
+	sess.Values["username"] = username
+	if err := sess.Save(r, w); err != nil {
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, pending.returnTo, http.StatusFound)
+}
+
+// LogoutHandler удаляет сессию администратора
+func LogoutHandler(w http.ResponseWriter, r *http.Request) {
+	sess, _ := store.Get(r, sessionName)
+	sess.Values = map[interface{}]interface{}{}
+	sess.Options.MaxAge = -1
+	_ = sess.Save(r, w)
+	http.Redirect(w, r, "/", http.StatusFound)
+}
+
+// AuthMiddleware пропускает только аутентифицированных администраторов.
+// Остальных отправляет на /auth/login с сохранением исходного адреса.
+func AuthMiddleware() mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			sess, err := store.Get(r, sessionName)
+			if err != nil || sess.Values["authenticated"] != true {
+				returnTo := url.Values{"next": {r.URL.RequestURI()}}
+				http.Redirect(w, r, "/auth/login?"+returnTo.Encode(), http.StatusFound)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// CurrentUser возвращает имя вошедшего администратора (пусто, если неизвестно)
+func CurrentUser(r *http.Request) string {
+	sess, err := store.Get(r, sessionName)
+	if err != nil {
+		return ""
+	}
+	username, _ := sess.Values["username"].(string)
+	return username
+}
+
+// safeReturnTo принимает только локальные пути админки, чтобы не было open redirect
+func safeReturnTo(next string) string {
+	if next != "/admin" && !strings.HasPrefix(next, "/admin/") ||
+		strings.ContainsAny(next, "\\\r\n") {
+		return defaultReturnTo
+	}
+	u, err := url.Parse(next)
+	if err != nil || u.IsAbs() || u.Host != "" {
+		return defaultReturnTo
+	}
+	return next
+}
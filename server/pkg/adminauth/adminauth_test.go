@@ -0,0 +1,28 @@
+package adminauth
+
+import "testing"
+
+func TestSafeReturnTo(t *testing.T) {
+	tests := []struct {
+		next string
+		want string
+	}{
+		{next: "", want: defaultReturnTo},
+		{next: "/admin/license-request?id=42", want: "/admin/license-request?id=42"},
+		{next: "/admin/organizations#quota", want: "/admin/organizations#quota"},
+		{next: "/admin", want: "/admin"},
+		{next: "/api/check-license", want: defaultReturnTo},
+		{next: "/adminfoo", want: defaultReturnTo},
+		{next: "/admin.evil.example", want: defaultReturnTo},
+		{next: "https://evil.example/admin", want: defaultReturnTo},
+		{next: "//evil.example/admin", want: defaultReturnTo},
+		{next: "/admin\\@evil.example", want: defaultReturnTo},
+		{next: "/admin\r\nLocation: https://evil.example", want: defaultReturnTo},
+		{next: "/admin/%zz", want: defaultReturnTo},
+	}
+	for _, tt := range tests {
+		if got := safeReturnTo(tt.next); got != tt.want {
+			t.Errorf("safeReturnTo(%q) = %q, want %q", tt.next, got, tt.want)
+		}
+	}
+}
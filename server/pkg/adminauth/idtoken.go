@@ -0,0 +1,74 @@
+package adminauth
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// idTokenClaims — поля id_token, которые нам нужны.
+// Подпись не проверяем: токен получен напрямую от token endpoint по TLS
+// (OpenID Connect Core, 3.1.3.7).
+type idTokenClaims struct {
+	Subject           string          `json:"sub"`
+	Audience          json.RawMessage `json:"aud"`
+	Expiry            int64           `json:"exp"`
+	Nonce             string          `json:"nonce"`
+	PreferredUsername string          `json:"preferred_username"`
+}
+
+func parseIDToken(raw string) (*idTokenClaims, error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("decode payload: %w", err)
+	}
+	var c idTokenClaims
+	if err := json.Unmarshal(payload, &c); err != nil {
+		return nil, fmt.Errorf("unmarshal payload: %w", err)
+	}
+	return &c, nil
+}
+
+func (c *idTokenClaims) validate(clientID, nonce string, now time.Time) error {
+	if c.Nonce != nonce {
+		return errors.New("nonce mismatch")
+	}
+	if !c.hasAudience(clientID) {
+		return errors.New("audience mismatch")
+	}
+	if c.Expiry != 0 && now.After(time.Unix(c.Expiry, 0)) {
+		return errors.New("token expired")
+	}
+	return nil
+}
+
+// aud может быть строкой или массивом строк
+func (c *idTokenClaims) hasAudience(clientID string) bool {
+	var single string
+	if err := json.Unmarshal(c.Audience, &single); err == nil {
+		return single == clientID
+	}
+	var list []string
+	if err := json.Unmarshal(c.Audience, &list); err == nil {
+		for _, a := range list {
+			if a == clientID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (c *idTokenClaims) username() string {
+	if c.PreferredUsername != "" {
+		return c.PreferredUsername
+	}
+	return c.Subject
+}
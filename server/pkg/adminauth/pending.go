@@ -0,0 +1,66 @@
+package adminauth
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"sync"
+	"time"
+)
+
+// pendingLogin — данные начатого, но не завершённого входа
+type pendingLogin struct {
+	nonce    string
+	verifier string
+	returnTo string
+	expires  time.Time
+}
+
+// pendingLogins хранит незавершённые логины по state.
+// Каждый state можно использовать только один раз.
+type pendingLogins struct {
+	mu    sync.Mutex
+	items map[string]*pendingLogin
+}
+
+func newPendingLogins() *pendingLogins {
+	return &pendingLogins{items: make(map[string]*pendingLogin)}
+}
+
+func (p *pendingLogins) put(state string, l *pendingLogin) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// Заодно вычищаем просроченные записи
+	now := time.Now()
+	for s, item := range p.items {
+		if now.After(item.expires) {
+			delete(p.items, s)
+		}
+	}
+	p.items[state] = l
+}
+
+// take возвращает логин по state и удаляет его; просроченные не возвращаются
+func (p *pendingLogins) take(state string) (*pendingLogin, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	l, ok := p.items[state]
+	if !ok {
+		return nil, false
+	}
+	delete(p.items, state)
+	if time.Now().After(l.expires) {
+		return nil, false
+	}
+	return l, true
+}
+
+// randomToken генерирует случайную строку для state и nonce
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
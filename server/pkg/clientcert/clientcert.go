@@ -0,0 +1,300 @@
+// Package clientcert — взаимный TLS с клиентами: выпуск клиентских сертификатов
+// при одобрении заявки и проверка сертификата, предъявленного клиентом.
+//
+// Сертификат выдаётся на ключ лицензии (CN = ключ) и подписывается CA из
+// CLIENT_CA_CERT/CLIENT_CA_KEY. Без ключа CA сервер только проверяет
+// сертификаты, выпущенные этим CA где-то ещё.
+package clientcert
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"example.com/licence-approval/server/pkg/metrics"
+	"example.com/licence-approval/server/pkg/tracing"
+)
+
+// Mode — режим взаимного TLS для /api/*
+type Mode string
+
+const (
+	// ModeOff — клиентские сертификаты не запрашиваются
+	ModeOff Mode = "off"
+	// ModeOptional — сертификат проверяется, если клиент его предъявил
+	ModeOptional Mode = "optional"
+	// ModeRequire — ключ, которому выдан сертификат, принимается только с ним;
+	// если сервер сам не выпускает сертификаты, сертификат нужен всегда
+	ModeRequire Mode = "require"
+)
+
+// RenewBefore — за сколько до истечения сертификат выпускается заново
+// (но не раньше, чем пройдут две трети срока его действия)
+const RenewBefore = 7 * 24 * time.Hour
+
+var (
+	ErrCertificateRequired = errors.New("client certificate is required")
+	ErrCertificateMismatch = errors.New("client certificate does not match the license key")
+	ErrCannotIssue         = errors.New("client CA key is not configured")
+)
+
+var (
+	mode     = ModeOff
+	caCert   *x509.Certificate
+	caKey    crypto.Signer
+	caPool   *x509.CertPool
+	validity time.Duration
+)
+
+// Configure включает взаимный TLS. caKeyPath может быть пустым — тогда сервер
+// только проверяет сертификаты. Вызывается при старте, до запуска сервера.
+func Configure(m Mode, caCertPath, caKeyPath string, certValidity time.Duration) error {
+	switch m {
+	case ModeOff, "":
+		mode = ModeOff
+		return nil
+	case ModeOptional, ModeRequire:
+	default:
+		return fmt.Errorf("unknown MTLS_MODE %q (off, optional or require)", m)
+	}
+	if caCertPath == "" {
+		return errors.New("CLIENT_CA_CERT is required for mutual TLS")
+	}
+
+	data, err := os.ReadFile(caCertPath)
+	if err != nil {
+		return fmt.Errorf("read client CA: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return fmt.Errorf("no PEM data in %s", caCertPath)
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return fmt.Errorf("parse client CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+
+	var key crypto.Signer
+	if caKeyPath != "" {
+		if key, err = loadSigner(caKeyPath); err != nil {
+			return err
+		}
+	}
+
+	mode, caCert, caKey, caPool, validity = m, cert, key, pool, certValidity
+	return nil
+}
+
+// Enabled — включён ли взаимный TLS
+func Enabled() bool {
+	return mode != ModeOff
+}
+
+// CA — сертификат клиентского УЦ (nil, если взаимный TLS выключен)
+func CA() *x509.Certificate {
+	return caCert
+}
+
+// CanIssue — может ли сервер сам выпускать клиентские сертификаты
+func CanIssue() bool {
+	return Enabled() && caKey != nil
+}
+
+// TLSConfig — настройки TLS для сервера. Сертификат запрашивается, но не
+// обязателен на уровне TLS: админка и первая заявка клиента работают без него,
+// а требование сертификата для /api/* проверяет Authorize.
+func TLSConfig() *tls.Config {
+	if !Enabled() {
+		return nil
+	}
+	return &tls.Config{
+		ClientAuth: tls.VerifyClientCertIfGiven,
+		ClientCAs:  caPool,
+		MinVersion: tls.VersionTLS12,
+	}
+}
+
+// Identity — ключ лицензии из проверенного клиентского сертификата (пусто, если
+// сертификата нет)
+func Identity(r *http.Request) string {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return ""
+	}
+	return r.TLS.VerifiedChains[0][0].Subject.CommonName
+}
+
+// Serial — серийный номер (hex, как при выпуске) проверенного клиентского
+// сертификата; пусто, если сертификата нет
+func Serial(r *http.Request) string {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return ""
+	}
+	return r.TLS.VerifiedChains[0][0].SerialNumber.Text(16)
+}
+
+// Authorize проверяет, что запрос к ключу licenseKey пришёл от его владельца.
+// issued — ключу уже выдан сертификат.
+func Authorize(r *http.Request, licenseKey string, issued bool) error {
+	if !Enabled() {
+		return nil
+	}
+	id := Identity(r)
+	if id != "" && id != licenseKey {
+		return ErrCertificateMismatch
+	}
+	if id == "" && mode == ModeRequire && (issued || !CanIssue()) {
+		return ErrCertificateRequired
+	}
+	return nil
+}
+
+// ParseCSR разбирает запрос на сертификат (PEM или DER в base64) и проверяет его подпись
+func ParseCSR(data string) (*x509.CertificateRequest, error) {
+	data = strings.TrimSpace(data)
+	var der []byte
+	if block, _ := pem.Decode([]byte(data)); block != nil {
+		der = block.Bytes
+	} else {
+		var err error
+		if der, err = base64.StdEncoding.DecodeString(data); err != nil {
+			return nil, fmt.Errorf("decode CSR: %w", err)
+		}
+	}
+	csr, err := x509.ParseCertificateRequest(der)
+	if err != nil {
+		return nil, fmt.Errorf("parse CSR: %w", err)
+	}
+	if err := csr.CheckSignature(); err != nil {
+		return nil, fmt.Errorf("CSR signature: %w", err)
+	}
+	return csr, nil
+}
+
+// Issue выпускает клиентский сертификат на ключ лицензии по CSR. Сертификат
+// удостоверяет только владельца ключа: действует ли лицензия, сервер проверяет
+// при каждом запросе. Возвращает сертификат в PEM и серийный номер.
+func Issue(ctx context.Context, csrData, licenseKey string) (string, string, error) {
+	if !CanIssue() {
+		return "", "", ErrCannotIssue
+	}
+	_, span := tracing.Start(ctx, "clientcert.Issue")
+	certPEM, serial, err := issue(csrData, licenseKey)
+	tracing.End(span, err)
+	metrics.CountSigning(metrics.SigningClientCertificate, err)
+	return certPEM, serial, err
+}
+
+func issue(csrData, licenseKey string) (string, string, error) {
+	csr, err := ParseCSR(csrData)
+	if err != nil {
+		return "", "", err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return "", "", err
+	}
+
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: licenseKey},
+		NotBefore:    now.Add(-5 * time.Minute),
+		NotAfter:     now.Add(validity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, caCert, csr.PublicKey, caKey)
+	if err != nil {
+		return "", "", fmt.Errorf("create certificate: %w", err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	return string(certPEM), serial.Text(16), nil
+}
+
+// NeedsReissue сообщает, что сохранённый сертификат certPEM нужно выпустить
+// заново: его нет, он скоро истекает или выдан на другой ключ, чем в CSR
+func NeedsReissue(certPEM, csrData string, now time.Time) bool {
+	if certPEM == "" {
+		return true
+	}
+	block, _ := pem.Decode([]byte(certPEM))
+	if block == nil {
+		return true
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil || now.Add(renewBefore(cert)).After(cert.NotAfter) {
+		return true
+	}
+	csr, err := ParseCSR(csrData)
+	if err != nil {
+		return false
+	}
+	pub, ok := cert.PublicKey.(interface{ Equal(crypto.PublicKey) bool })
+	return !ok || !pub.Equal(csr.PublicKey)
+}
+
+// SameKey сообщает, что оба CSR корректны и запрашивают сертификат на один и тот же ключ
+func SameKey(csrA, csrB string) bool {
+	a, err := ParseCSR(csrA)
+	if err != nil {
+		return false
+	}
+	b, err := ParseCSR(csrB)
+	if err != nil {
+		return false
+	}
+	pub, ok := a.PublicKey.(interface{ Equal(crypto.PublicKey) bool })
+	return ok && pub.Equal(b.PublicKey)
+}
+
+func renewBefore(cert *x509.Certificate) time.Duration {
+	if third := cert.NotAfter.Sub(cert.NotBefore) / 3; third < RenewBefore {
+		return third
+	}
+	return RenewBefore
+}
+
+func loadSigner(path string) (crypto.Signer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read client CA key: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data in %s", path)
+	}
+
+	var key interface{}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		err = fmt.Errorf("unsupported PEM type %q", block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse client CA key %s: %w", path, err)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("client CA key %s cannot sign", path)
+	}
+	return signer, nil
+}
@@ -0,0 +1,144 @@
+// Package health — проверки для оркестратора: /healthz (процесс жив),
+// /readyz (сервер может обслуживать запросы) и /version (сведения о сборке).
+package health
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// checkTimeout ограничивает все проверки готовности: зависшая БД должна
+// дать ответ «не готов», а не таймаут пробы
+const checkTimeout = 3 * time.Second
+
+// Check — проверка готовности; nil — всё в порядке
+type Check func(ctx context.Context) error
+
+var (
+	mu     sync.RWMutex
+	checks = make(map[string]Check)
+)
+
+// Register добавляет проверку готовности name
+func Register(name string, check Check) {
+	mu.Lock()
+	defer mu.Unlock()
+	checks[name] = check
+}
+
+// checkResult — результат одной проверки в ответе /readyz. /readyz доступен
+// без аутентификации, поэтому текст ошибки в ответ не попадает — только в журнал.
+type checkResult struct {
+	Status string `json:"status"`
+}
+
+type readyResponse struct {
+	Status string                 `json:"status"`
+	Checks map[string]checkResult `json:"checks"`
+}
+
+// HealthzHandler — GET /healthz: процесс отвечает. Зависимости не проверяются,
+// чтобы недоступная БД не приводила к перезапуску сервера.
+func HealthzHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// ReadyzHandler — GET /readyz: все проверки выполняются параллельно;
+// 503, если хотя бы одна не прошла
+func ReadyzHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), checkTimeout)
+	defer cancel()
+
+	mu.RLock()
+	names := make([]string, 0, len(checks))
+	for name := range checks {
+		names = append(names, name)
+	}
+	mu.RUnlock()
+	sort.Strings(names)
+
+	results := make([]error, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		mu.RLock()
+		check := checks[name]
+		mu.RUnlock()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = run(ctx, check)
+		}()
+	}
+	wg.Wait()
+
+	resp := readyResponse{Status: "ready", Checks: make(map[string]checkResult, len(names))}
+	status := http.StatusOK
+	for i, name := range names {
+		if err := results[i]; err != nil {
+			resp.Checks[name] = checkResult{Status: "fail"}
+			resp.Status, status = "not_ready", http.StatusServiceUnavailable
+			slog.WarnContext(r.Context(), "Readiness check failed", "check", name, "error", err)
+			continue
+		}
+		resp.Checks[name] = checkResult{Status: "ok"}
+	}
+	writeJSON(w, status, resp)
+}
+
+// run выполняет проверку, не дожидаясь её дольше ctx
+func run(ctx context.Context, check Check) error {
+	done := make(chan error, 1)
+	go func() { done <- check(ctx) }()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("timed out after %s", checkTimeout)
+	}
+}
+
+// CertificateCheck проверяет пару сертификат/ключ TLS: файлы читаются, ключ
+// подходит к сертификату и тот действует ещё не меньше minValidity
+func CertificateCheck(certFile, keyFile string, minValidity time.Duration) Check {
+	return func(ctx context.Context) error {
+		pair, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return err
+		}
+		leaf, err := x509.ParseCertificate(pair.Certificate[0])
+		if err != nil {
+			return err
+		}
+		return CheckExpiry(leaf, minValidity, time.Now())
+	}
+}
+
+// CheckExpiry — сертификат уже действует и не истекает в ближайшие minValidity
+func CheckExpiry(cert *x509.Certificate, minValidity time.Duration, now time.Time) error {
+	switch {
+	case now.Before(cert.NotBefore):
+		return fmt.Errorf("certificate %q is not valid before %s", cert.Subject.CommonName, cert.NotBefore.Format(time.RFC3339))
+	case now.After(cert.NotAfter):
+		return fmt.Errorf("certificate %q expired at %s", cert.Subject.CommonName, cert.NotAfter.Format(time.RFC3339))
+	case now.Add(minValidity).After(cert.NotAfter):
+		return fmt.Errorf("certificate %q expires soon, at %s", cert.Subject.CommonName, cert.NotAfter.Format(time.RFC3339))
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Warn("Error writing health response", "error", err)
+	}
+}
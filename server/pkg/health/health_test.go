@@ -0,0 +1,84 @@
+package health
+
+import (
+	"context"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckExpiry(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	const minValidity = 7 * 24 * time.Hour
+
+	tests := []struct {
+		name      string
+		notBefore time.Time
+		notAfter  time.Time
+		wantErr   string
+	}{
+		{name: "valid", notBefore: now.AddDate(0, -1, 0), notAfter: now.AddDate(0, 6, 0)},
+		{name: "exactly min validity left", notBefore: now.AddDate(0, -1, 0), notAfter: now.Add(minValidity)},
+		{name: "expires soon", notBefore: now.AddDate(0, -1, 0), notAfter: now.Add(minValidity - time.Minute), wantErr: "expires soon"},
+		{name: "expired", notBefore: now.AddDate(-1, 0, 0), notAfter: now.Add(-time.Second), wantErr: "expired at"},
+		{name: "not yet valid", notBefore: now.Add(time.Hour), notAfter: now.AddDate(1, 0, 0), wantErr: "not valid before"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cert := &x509.Certificate{
+				Subject:   pkix.Name{CommonName: "license.example.com"},
+				NotBefore: tt.notBefore,
+				NotAfter:  tt.notAfter,
+			}
+			err := CheckExpiry(cert, minValidity, now)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("CheckExpiry() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("CheckExpiry() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestReadyzHidesErrors(t *testing.T) {
+	mu.Lock()
+	saved := checks
+	checks = map[string]Check{
+		"database": func(ctx context.Context) error { return errors.New("dial tcp 10.0.0.5:5432: connection refused") },
+		"signing":  func(ctx context.Context) error { return nil },
+	}
+	mu.Unlock()
+	t.Cleanup(func() {
+		mu.Lock()
+		checks = saved
+		mu.Unlock()
+	})
+
+	rec := httptest.NewRecorder()
+	ReadyzHandler(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+	// Подробности ошибки остаются в журнале, наружу — только ok/fail
+	if strings.Contains(rec.Body.String(), "10.0.0.5") {
+		t.Errorf("response leaks the check error: %s", rec.Body.String())
+	}
+	var resp readyResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Status != "not_ready" || resp.Checks["database"].Status != "fail" || resp.Checks["signing"].Status != "ok" {
+		t.Errorf("response = %+v", resp)
+	}
+}
@@ -0,0 +1,46 @@
+package health
+
+import (
+	"net/http"
+	"runtime"
+	"runtime/debug"
+)
+
+// Version — версия релиза; задаётся при сборке:
+//
+//	go build -ldflags "-X example.com/licence-approval/server/pkg/health.Version=1.4.0"
+var Version = "dev"
+
+// BuildInfo — ответ /version
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Revision  string `json:"revision,omitempty"`
+	BuildTime string `json:"build_time,omitempty"`
+	Modified  bool   `json:"modified,omitempty"`
+	GoVersion string `json:"go_version"`
+}
+
+// ReadBuildInfo собирает версию и данные VCS, которые go build встраивает в бинарник
+func ReadBuildInfo() BuildInfo {
+	info := BuildInfo{Version: Version, GoVersion: runtime.Version()}
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			info.Revision = s.Value
+		case "vcs.time":
+			info.BuildTime = s.Value
+		case "vcs.modified":
+			info.Modified = s.Value == "true"
+		}
+	}
+	return info
+}
+
+// VersionHandler — GET /version: сведения о сборке
+func VersionHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, ReadBuildInfo())
+}
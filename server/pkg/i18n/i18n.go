@@ -0,0 +1,175 @@
+// Package i18n — каталог сообщений админки и API на русском и английском
+// и выбор языка запроса: явный выбор пользователя, затем Accept-Language.
+package i18n
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Поддерживаемые языки
+const (
+	Russian = "ru"
+	English = "en"
+)
+
+// Supported — языки каталога в порядке показа в переключателе
+var Supported = []string{Russian, English}
+
+// Языки по умолчанию: админка исторически на русском, API — на английском
+const (
+	DefaultAdmin = Russian
+	DefaultAPI   = English
+)
+
+// CookieName — cookie с языком, выбранным в админке
+const CookieName = "lang"
+
+// T возвращает сообщение key на языке lang, подставляя args через fmt.Sprintf.
+// Без перевода берётся английский текст, а если нет и его — сам ключ.
+func T(lang, key string, args ...interface{}) string {
+	msg, ok := Lookup(lang, key)
+	if !ok {
+		msg = key
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(msg, args...)
+	}
+	return msg
+}
+
+// Lookup возвращает шаблон сообщения key (с откатом на английский) и есть ли он в каталоге
+func Lookup(lang, key string) (string, bool) {
+	if msg, ok := messages[key][lang]; ok {
+		return msg, true
+	}
+	msg, ok := messages[key][English]
+	return msg, ok
+}
+
+// Duration — длительность для человека с точностью до двух единиц: «2 д 3 ч», «5 мин»
+func Duration(lang string, d time.Duration) string {
+	d = d.Round(time.Minute)
+	days := int(d / (24 * time.Hour))
+	hours := int(d % (24 * time.Hour) / time.Hour)
+	minutes := int(d % time.Hour / time.Minute)
+	switch {
+	case days > 0:
+		return T(lang, "duration.days_hours", days, hours)
+	case hours > 0:
+		return T(lang, "duration.hours_minutes", hours, minutes)
+	}
+	return T(lang, "duration.minutes", minutes)
+}
+
+// IsSupported — есть ли язык в каталоге
+func IsSupported(lang string) bool {
+	for _, l := range Supported {
+		if l == lang {
+			return true
+		}
+	}
+	return false
+}
+
+// AdminLanguage — язык страниц админки: выбранный пользователем (cookie),
+// иначе из Accept-Language, иначе DefaultAdmin
+func AdminLanguage(r *http.Request) string {
+	if c, err := r.Cookie(CookieName); err == nil && IsSupported(c.Value) {
+		return c.Value
+	}
+	return Negotiate(r.Header.Get("Accept-Language"), DefaultAdmin)
+}
+
+// APILanguage — язык сообщений API: параметр lang или Accept-Language, иначе DefaultAPI
+func APILanguage(r *http.Request) string {
+	if lang := r.URL.Query().Get("lang"); IsSupported(lang) {
+		return lang
+	}
+	return Negotiate(r.Header.Get("Accept-Language"), DefaultAPI)
+}
+
+// Negotiate выбирает поддерживаемый язык из заголовка Accept-Language с учётом
+// весов q; региональные варианты (en-US) сводятся к основному языку
+func Negotiate(header, fallback string) string {
+	type candidate struct {
+		lang string
+		q    float64
+	}
+	var list []candidate
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if tag == "" {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q <= 0 {
+			continue
+		}
+		primary, _, _ := strings.Cut(strings.ToLower(tag), "-")
+		list = append(list, candidate{primary, q})
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].q > list[j].q })
+	for _, c := range list {
+		if c.lang == "*" {
+			return fallback
+		}
+		if IsSupported(c.lang) {
+			return c.lang
+		}
+	}
+	return fallback
+}
+
+// LanguageHandler — GET /admin/language?lang=en: запоминает выбор в cookie
+// и возвращает на страницу, с которой пришёл пользователь
+func LanguageHandler(w http.ResponseWriter, r *http.Request) {
+	lang := r.URL.Query().Get("lang")
+	if !IsSupported(lang) {
+		http.Error(w, "Unsupported language", http.StatusBadRequest)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     CookieName,
+		Value:    lang,
+		Path:     "/admin",
+		MaxAge:   365 * 24 * 60 * 60,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, returnPath(r.Referer()), http.StatusSeeOther)
+}
+
+// returnPath — локальный путь админки из Referer, чтобы не было open redirect
+func returnPath(referer string) string {
+	const fallback = "/admin/license-requests"
+	if referer == "" {
+		return fallback
+	}
+	// Нужны только путь и запрос: схема и хост Referer не используются
+	if i := strings.Index(referer, "://"); i >= 0 {
+		rest := referer[i+3:]
+		j := strings.IndexByte(rest, '/')
+		if j < 0 {
+			return fallback
+		}
+		referer = rest[j:]
+	}
+	if !strings.HasPrefix(referer, "/admin") || strings.HasPrefix(referer, "//") ||
+		strings.ContainsAny(referer, "\\\r\n") {
+		return fallback
+	}
+	return referer
+}
@@ -0,0 +1,69 @@
+package i18n
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		header   string
+		fallback string
+		want     string
+	}{
+		{header: "", fallback: Russian, want: Russian},
+		{header: "en", fallback: Russian, want: English},
+		{header: "en-US,en;q=0.9", fallback: Russian, want: English},
+		{header: "RU-ru", fallback: English, want: Russian},
+		// Порядок определяют веса, а не позиция в заголовке
+		{header: "en;q=0.5, ru;q=0.8", fallback: English, want: Russian},
+		{header: "de-DE, fr;q=0.9, en;q=0.1", fallback: Russian, want: English},
+		{header: "de, fr", fallback: Russian, want: Russian},
+		// При равных весах побеждает первый
+		{header: "ru;q=0.7, en;q=0.7", fallback: English, want: Russian},
+		// q=0 — язык не принимается
+		{header: "en;q=0, ru;q=0.1", fallback: English, want: Russian},
+		{header: "en;q=0", fallback: Russian, want: Russian},
+		{header: "*", fallback: English, want: English},
+		{header: "de, *;q=0.5, ru;q=0.3", fallback: English, want: English},
+		// Неразборчивый вес пропускает только свой элемент
+		{header: "en;q=abc, ru;q=0.2", fallback: English, want: Russian},
+		{header: " , ;q=1, en", fallback: Russian, want: English},
+	}
+	for _, tt := range tests {
+		if got := Negotiate(tt.header, tt.fallback); got != tt.want {
+			t.Errorf("Negotiate(%q, %q) = %q, want %q", tt.header, tt.fallback, got, tt.want)
+		}
+	}
+}
+
+func TestAdminLanguage(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/admin/license-requests", nil)
+	r.Header.Set("Accept-Language", "en-GB")
+	if got := AdminLanguage(r); got != English {
+		t.Errorf("AdminLanguage() = %q, want %q", got, English)
+	}
+	// Выбор в cookie важнее заголовка; неизвестное значение cookie игнорируется
+	r.AddCookie(&http.Cookie{Name: CookieName, Value: Russian})
+	if got := AdminLanguage(r); got != Russian {
+		t.Errorf("AdminLanguage() with cookie = %q, want %q", got, Russian)
+	}
+	r = httptest.NewRequest(http.MethodGet, "/admin/license-requests", nil)
+	r.AddCookie(&http.Cookie{Name: CookieName, Value: "de"})
+	if got := AdminLanguage(r); got != DefaultAdmin {
+		t.Errorf("AdminLanguage() with unknown cookie = %q, want %q", got, DefaultAdmin)
+	}
+}
+
+func TestAPILanguage(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/api/check-license?lang=ru", nil)
+	r.Header.Set("Accept-Language", "en")
+	if got := APILanguage(r); got != Russian {
+		t.Errorf("APILanguage() = %q, want %q", got, Russian)
+	}
+	r = httptest.NewRequest(http.MethodGet, "/api/check-license?lang=de", nil)
+	if got := APILanguage(r); got != DefaultAPI {
+		t.Errorf("APILanguage() with unsupported lang = %q, want %q", got, DefaultAPI)
+	}
+}
@@ -0,0 +1,226 @@
+package i18n
+
+// messages — каталог: ключ сообщения → перевод по языкам. Переводы одного
+// сообщения стоят рядом, чтобы при добавлении текста не забыть второй язык.
+// Аргументы подставляются по правилам fmt (%s, %d).
+var messages = map[string]map[string]string{
+	// Переключатель языка
+	"language.ru": {Russian: "RU", English: "RU"},
+	"language.en": {Russian: "EN", English: "EN"},
+
+	// Меню
+	"nav.dashboard":     {Russian: "Панель", English: "Dashboard"},
+	"nav.requests":      {Russian: "Заявки", English: "Requests"},
+	"nav.products":      {Russian: "Продукты", English: "Products"},
+	"nav.organizations": {Russian: "Организации", English: "Organizations"},
+	"nav.policy":        {Russian: "Правила", English: "Policy"},
+	"nav.offline":       {Russian: "Офлайн-активация", English: "Offline activation"},
+
+	// Общие действия
+	"action.add":           {Russian: "Добавить", English: "Add"},
+	"action.approve":       {Russian: "Одобрить", English: "Approve"},
+	"action.cancel":        {Russian: "Отмена", English: "Cancel"},
+	"action.create":        {Russian: "Создать", English: "Create"},
+	"action.reject":        {Russian: "Отклонить", English: "Reject"},
+	"action.release":       {Russian: "Деактивировать", English: "Deactivate"},
+	"action.response_file": {Russian: "Файл ответа", English: "Response file"},
+	"action.transfer":      {Russian: "Перенести", English: "Transfer"},
+
+	// Статусы заявки
+	"status.pending":              {Russian: "В ожидании", English: "Pending"},
+	"status.approved":             {Russian: "Одобрена", English: "Approved"},
+	"status.trial_until":          {Russian: "Пробная до %s", English: "Trial until %s"},
+	"status.conversion_requested": {Russian: "Запрошена полная", English: "Full license requested"},
+	"status.rejected":             {Russian: "Отклонена", English: "Rejected"},
+	"status.released":             {Russian: "Деактивирована", English: "Deactivated"},
+	"status.transferred":          {Russian: "Перенесена", English: "Transferred"},
+	"status.offline":              {Russian: "Офлайн", English: "Offline"},
+
+	// Действия в истории заявки
+	"event.created":               {Russian: "Заявка создана", English: "Request created"},
+	"event.approved":              {Russian: "Одобрена", English: "Approved"},
+	"event.rejected":              {Russian: "Отклонена", English: "Rejected"},
+	"event.released":              {Russian: "Деактивирована клиентом", English: "Deactivated by the client"},
+	"event.transferred":           {Russian: "Перенесена", English: "Transferred"},
+	"event.conversion_requested":  {Russian: "Запрошен перевод в полную", English: "Full license requested"},
+	"event.organization_assigned": {Russian: "Изменена организация", English: "Organization changed"},
+	"event.policy_decision":       {Russian: "Решение правил", English: "Policy decision"},
+	"event.certificate_issued":    {Russian: "Выпущен клиентский сертификат", English: "Client certificate issued"},
+
+	// Длительности
+	"duration.days_hours":    {Russian: "%d д %d ч", English: "%dd %dh"},
+	"duration.hours_minutes": {Russian: "%d ч %d мин", English: "%dh %dm"},
+	"duration.minutes":       {Russian: "%d мин", English: "%d min"},
+
+	// Панель
+	"dashboard.title":             {Russian: "Панель", English: "Dashboard"},
+	"dashboard.period":            {Russian: "Период", English: "Period"},
+	"dashboard.days":              {Russian: "%d дн.", English: "%d days"},
+	"dashboard.from":              {Russian: "С", English: "From"},
+	"dashboard.to":                {Russian: "по", English: "to"},
+	"dashboard.show":              {Russian: "Показать", English: "Show"},
+	"dashboard.range":             {Russian: "%s — %s", English: "%s to %s"},
+	"dashboard.pending":           {Russian: "В очереди", English: "Pending"},
+	"dashboard.pending_oldest":    {Russian: "Самая старая: %s", English: "Oldest: %s"},
+	"dashboard.pending_median":    {Russian: "Медиана ожидания: %s", English: "Median wait: %s"},
+	"dashboard.pending_older":     {Russian: "Старше суток: %d, старше недели: %d", English: "Older than a day: %d, than a week: %d"},
+	"dashboard.approved":          {Russian: "Одобрено", English: "Approved"},
+	"dashboard.rejected":          {Russian: "Отклонено", English: "Rejected"},
+	"dashboard.approval_rate":     {Russian: "Доля одобренных: %d%%", English: "Approval rate: %d%%"},
+	"dashboard.time_to_approve":   {Russian: "Медиана до одобрения", English: "Median time to approve"},
+	"dashboard.time_to_approve_n": {Russian: "По %d одобрениям, без пробных", English: "Over %d approvals, trials excluded"},
+	"dashboard.no_data":           {Russian: "Нет данных", English: "No data"},
+	"dashboard.by_day":            {Russian: "Решения по дням", English: "Decisions by day"},
+	"dashboard.day":               {Russian: "День", English: "Day"},
+	"dashboard.by_tag":            {Russian: "Решения по TAG", English: "Decisions by TAG"},
+	"dashboard.no_tag":            {Russian: "без TAG", English: "no TAG"},
+	"dashboard.top_requesters":    {Russian: "Частые запрашивающие", English: "Top requesters"},
+	"dashboard.requester_unknown": {Russian: "не указан", English: "unknown"},
+	"dashboard.total":             {Russian: "Всего", English: "Total"},
+	"dashboard.history_note":      {Russian: "Решения считаются по истории заявок; перенос лицензии не считается новым решением.", English: "Decisions are counted from request history; license transfers are not counted as new decisions."},
+
+	// Список заявок
+	"requests.title":   {Russian: "Запросы на Лицензии", English: "License Requests"},
+	"requests.all":     {Russian: "Все", English: "All"},
+	"requests.version": {Russian: "версия %s", English: "version %s"},
+	"requests.details": {Russian: "Подробнее", English: "Details"},
+	"requests.policy":  {Russian: "Правила: %s", English: "Policy: %s"},
+
+	// Заявка
+	"request.title":              {Russian: "Заявка #%d", English: "Request #%d"},
+	"request.back":               {Russian: "Все заявки", English: "All requests"},
+	"request.info":               {Russian: "Сведения", English: "Details"},
+	"request.license_key":        {Russian: "Ключ лицензии", English: "License key"},
+	"request.product":            {Russian: "Продукт", English: "Product"},
+	"request.organization":       {Russian: "Организация", English: "Organization"},
+	"request.requester":          {Russian: "Запрашивающий", English: "Requester"},
+	"request.justification":      {Russian: "Обоснование", English: "Justification"},
+	"request.machine":            {Russian: "Машина", English: "Machine"},
+	"request.fingerprint":        {Russian: "Отпечаток машины", English: "Machine fingerprint"},
+	"request.app_version":        {Russian: "Версия приложения", English: "Application version"},
+	"request.status":             {Russian: "Статус", English: "Status"},
+	"request.created":            {Russian: "Создана", English: "Created"},
+	"request.decided":            {Russian: "Решение", English: "Decided"},
+	"request.expires":            {Russian: "Действует до", English: "Expires"},
+	"request.policy":             {Russian: "Правила", English: "Policy"},
+	"request.client_certificate": {Russian: "Клиентский сертификат", English: "Client certificate"},
+	"request.metadata":           {Russian: "Метаданные", English: "Metadata"},
+	"request.actions":            {Russian: "Действия", English: "Actions"},
+	"request.no_actions":         {Russian: "Нет доступных действий", English: "No actions available"},
+	"request.related":            {Russian: "Связанные заявки", English: "Related requests"},
+	"request.license":            {Russian: "Лицензия", English: "License"},
+	"request.signature":          {Russian: "Подпись", English: "Signature"},
+
+	"reject.title":   {Russian: "Подтверждение Отклонения", English: "Confirm Rejection"},
+	"reject.confirm": {Russian: "Вы уверены, что хотите отклонить заявку ID %d?", English: "Are you sure you want to reject request ID %d?"},
+
+	"transfer.new_key":     {Russian: "Новый ключ", English: "New key"},
+	"transfer.fingerprint": {Russian: "Отпечаток (необязательно)", English: "Fingerprint (optional)"},
+
+	"history.title":  {Russian: "История", English: "History"},
+	"history.time":   {Russian: "Время", English: "Time"},
+	"history.event":  {Russian: "Событие", English: "Event"},
+	"history.status": {Russian: "Статус", English: "Status"},
+	"history.actor":  {Russian: "Кто", English: "Actor"},
+	"history.detail": {Russian: "Подробности", English: "Details"},
+	"history.empty":  {Russian: "История не записана (заявка создана до её появления)", English: "No history recorded (the request predates it)"},
+
+	"checkins.title":  {Russian: "Проверки лицензии", English: "License checks"},
+	"checkins.last":   {Russian: "Последняя", English: "Last"},
+	"checkins.first":  {Russian: "Первая", English: "First"},
+	"checkins.status": {Russian: "Ответ", English: "Response"},
+	"checkins.count":  {Russian: "Проверок", English: "Checks"},
+	"checkins.empty":  {Russian: "Клиент ещё не проверял лицензию", English: "The client has not checked the license yet"},
+
+	// Продукты
+	"products.title":                     {Russian: "Продукты", English: "Products"},
+	"products.code":                      {Russian: "Код", English: "Code"},
+	"products.name":                      {Russian: "Название", English: "Name"},
+	"products.signing_key":               {Russian: "Ключ подписи", English: "Signing key"},
+	"products.signing_key_path":          {Russian: "Путь к ключу подписи (PEM)", English: "Signing key path (PEM)"},
+	"products.public_key":                {Russian: "Открытый ключ для клиентов", English: "Public key for clients"},
+	"products.default_tag":               {Russian: "TAG по умолчанию", English: "Default TAG"},
+	"products.default_entitlements":      {Russian: "Права по умолчанию", English: "Default entitlements"},
+	"products.default_entitlements_json": {Russian: "Права по умолчанию (JSON)", English: "Default entitlements (JSON)"},
+	"products.versions":                  {Russian: "Версии", English: "Versions"},
+	"products.new":                       {Russian: "Новый продукт", English: "New product"},
+
+	// Организации
+	"organizations.title":        {Russian: "Организации", English: "Organizations"},
+	"organizations.claim":        {Russian: "Идентификатор:", English: "Identifier:"},
+	"organizations.claim_hint":   {Russian: "Идентификатор для клиентов (например, домен)", English: "Identifier for clients (e.g. a domain)"},
+	"organizations.invite_code":  {Russian: "Код приглашения:", English: "Invite code:"},
+	"organizations.quotas":       {Russian: "Квоты", English: "Quotas"},
+	"organizations.quota":        {Russian: "Квота", English: "Quota"},
+	"organizations.used":         {Russian: "Использовано", English: "Used"},
+	"organizations.no_quotas":    {Russian: "Квоты не заданы", English: "No quotas set"},
+	"organizations.set_quota":    {Russian: "Задать квоту", English: "Set quota"},
+	"organizations.contacts":     {Russian: "Контакты", English: "Contacts"},
+	"organizations.no_contacts":  {Russian: "Контактов нет", English: "No contacts"},
+	"organizations.contact_name": {Russian: "Имя", English: "Name"},
+	"organizations.contact_role": {Russian: "Роль", English: "Role"},
+	"organizations.add_contact":  {Russian: "Добавить контакт", English: "Add contact"},
+	"organizations.new":          {Russian: "Новая организация", English: "New organization"},
+	"organizations.name":         {Russian: "Название", English: "Name"},
+
+	// Правила автоматического решения
+	"policy.title":        {Russian: "Правила автоматического решения", English: "Auto-decision policy"},
+	"policy.dry_run_mode": {Russian: "Политика в режиме dry-run: решения только записываются", English: "Policy is in dry-run mode: decisions are only recorded"},
+	"policy.active":       {Russian: "Политика применяется к новым заявкам", English: "Policy applies to new requests"},
+	"policy.inactive":     {Russian: "Политика не задана (POLICY_FILE): все заявки решаются вручную", English: "No policy configured (POLICY_FILE): all requests are decided manually"},
+	"policy.rules":        {Russian: "Правила (JSON)", English: "Rules (JSON)"},
+	"policy.days":         {Russian: "Заявки за последние дни:", English: "Requests from the last days:"},
+	"policy.requested_by": {Russian: "Запросил", English: "Requested by"},
+	"policy.host":         {Russian: "Хост", English: "Host"},
+	"policy.actual":       {Russian: "Фактически", English: "Actual"},
+	"policy.by_rules":     {Russian: "По правилам", English: "By policy"},
+
+	// Офлайн-активация
+	"offline.title": {Russian: "Офлайн-активация", English: "Offline activation"},
+	"offline.intro": {
+		Russian: "Для машин без доступа к серверу лицензий: пользователь выполняет «%s» и передаёт файл заявки. " +
+			"Загрузите его здесь — заявка появится в списке, и после одобрения на её странице можно скачать " +
+			"подписанный файл ответа, который пользователь импортирует командой «%s».",
+		English: "For machines without access to the license server: the user runs \"%s\" and hands over the request file. " +
+			"Upload it here — the request is registered, and once it is approved its page offers a signed response file, " +
+			"which the user imports with \"%s\".",
+	},
+	"offline.request_file": {Russian: "Файл заявки", English: "Request file"},
+	"offline.submit":       {Russian: "Загрузить заявку", English: "Upload request"},
+	"offline.pending_note": {
+		Russian: "Заявка отмечается в списке как «Офлайн». Повторная загрузка того же файла ведёт к уже " +
+			"существующей заявке и не меняет решение по ней.",
+		English: "The request is marked \"Offline\" in the request list. Uploading the same file again leads " +
+			"to the existing request and does not change its decision.",
+	},
+
+	// Сообщения API (поле message)
+	"api.license_active":        {Russian: "Лицензия активна.", English: "License is active."},
+	"api.license_not_active":    {Russian: "Лицензия не активна.", English: "License is not active."},
+	"api.trial_expired":         {Russian: "Срок пробной лицензии истёк.", English: "Trial license has expired."},
+	"api.request_pending":       {Russian: "Заявка на лицензию ожидает решения.", English: "License request is pending."},
+	"api.request_rejected":      {Russian: "Заявка на лицензию отклонена.", English: "License request has been rejected."},
+	"api.license_deactivated":   {Russian: "Лицензия деактивирована.", English: "License has been deactivated."},
+	"api.license_transferred":   {Russian: "Лицензия перенесена на другую машину.", English: "License has been transferred to another machine."},
+	"api.request_created":       {Russian: "Заявка на лицензию создана.", English: "License request created."},
+	"api.request_auto_approved": {Russian: "Заявка на лицензию одобрена автоматически.", English: "License request approved automatically."},
+	"api.trial_approved":        {Russian: "Пробная лицензия выдана.", English: "Trial license approved."},
+
+	// Заголовки ошибок API (поле title в problem+json), по коду ошибки
+	"problem.bad_request":                 {Russian: "Некорректный запрос", English: "Bad request"},
+	"problem.license_key_required":        {Russian: "Не указан ключ лицензии", English: "License key is required"},
+	"problem.unknown_product":             {Russian: "Неизвестный продукт", English: "Unknown product"},
+	"problem.unknown_version":             {Russian: "Неизвестная версия продукта", English: "Unknown product version"},
+	"problem.unknown_invite_code":         {Russian: "Неизвестный код приглашения", English: "Unknown invite code"},
+	"problem.request_exists":              {Russian: "Заявка на лицензию уже существует", English: "License request already exists"},
+	"problem.fingerprint_required":        {Russian: "Нужен отпечаток машины", English: "Machine fingerprint is required"},
+	"problem.trial_used":                  {Russian: "Пробная лицензия на этой машине уже использована", English: "Trial already used on this machine"},
+	"problem.not_active":                  {Russian: "Лицензия не активна", English: "License is not active"},
+	"problem.fingerprint_mismatch":        {Russian: "Лицензия активирована на другой машине", English: "License is activated on another machine"},
+	"problem.invalid_csr":                 {Russian: "Некорректный запрос клиентского сертификата", English: "Invalid client certificate request"},
+	"problem.client_certificate_required": {Russian: "Нужен клиентский сертификат", English: "Client certificate is required"},
+	"problem.client_certificate_mismatch": {Russian: "Клиентский сертификат не соответствует ключу лицензии", English: "Client certificate does not match the license key"},
+	"problem.not_found":                   {Russian: "Не найдено", English: "Not found"},
+	"problem.method_not_allowed":          {Russian: "Метод не поддерживается", English: "Method not allowed"},
+	"problem.internal_error":              {Russian: "Внутренняя ошибка", English: "Internal error"},
+}
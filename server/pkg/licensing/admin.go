@@ -0,0 +1,252 @@
+package licensing
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"example.com/licence-approval/server/pkg/adminauth"
+	"example.com/licence-approval/server/pkg/orgs"
+)
+
+// requestsPage — данные для admin_requests.html
+type requestsPage struct {
+	Products []Product
+	Pending  map[string]int
+	Current  string // код выбранного продукта; пусто — все продукты
+	Requests []LicenseRequest
+	Orgs     map[int]string
+}
+
+// GetLicenseRequestsHandler показывает очередь заявок, разбитую по продуктам
+func GetLicenseRequestsHandler(w http.ResponseWriter, r *http.Request) {
+	current := r.URL.Query().Get("product")
+
+	products, err := ListProducts()
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error listing products", "error", err)
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+	pending, err := PendingCounts()
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error counting pending requests", "error", err)
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+	requests, err := ListRequests(current)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error listing license requests", "error", err)
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+	orgNames, err := orgs.Names()
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error listing organizations", "error", err)
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+
+	err = tmpl.Render(w, r, "admin_requests.html", requestsPage{
+		Products: products,
+		Pending:  pending,
+		Current:  current,
+		Requests: requests,
+		Orgs:     orgNames,
+	})
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error rendering license requests", "error", err)
+	}
+}
+
+// checkInHistoryLimit — сколько записей о проверках показывать на странице заявки
+const checkInHistoryLimit = 50
+
+// requestPage — данные для admin_request.html
+type requestPage struct {
+	Request  *LicenseRequest
+	Product  *Product
+	Orgs     map[int]string
+	Events   []Event
+	CheckIns []CheckIn
+	// Related — другие заявки того же ключа и цепочки переносов лицензии
+	Related []LicenseRequest
+	// License — подписанное содержимое лицензии, отформатированное для чтения
+	License string
+}
+
+// GetLicenseRequestHandler — страница заявки: сведения, история, проверки и действия
+func GetLicenseRequestHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.FormValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid request ID", http.StatusBadRequest)
+		return
+	}
+	lr, err := GetRequest(r.Context(), id)
+	if err == ErrRequestNotFound {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error loading license request", "license_request_id", id, "error", err)
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+
+	page := requestPage{Request: lr}
+	if page.Product, err = GetProduct(r.Context(), lr.ProductCode); err != nil && err != ErrProductNotFound {
+		slog.ErrorContext(r.Context(), "Error loading product", "product", lr.ProductCode, "error", err)
+	}
+	if page.Orgs, err = orgs.Names(); err != nil {
+		slog.ErrorContext(r.Context(), "Error listing organizations", "error", err)
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+	if page.Events, err = ListEvents(id); err != nil {
+		slog.ErrorContext(r.Context(), "Error loading request history", "license_request_id", id, "error", err)
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+	if page.CheckIns, err = ListCheckIns(id, checkInHistoryLimit); err != nil {
+		slog.ErrorContext(r.Context(), "Error loading request check-ins", "license_request_id", id, "error", err)
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+	if page.Related, err = ListRelatedRequests(lr); err != nil {
+		slog.ErrorContext(r.Context(), "Error loading related requests", "license_request_id", id, "error", err)
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+	if lr.LicenseData.Valid {
+		var buf bytes.Buffer
+		if err := json.Indent(&buf, []byte(lr.LicenseData.String), "", "  "); err == nil {
+			page.License = buf.String()
+		} else {
+			page.License = lr.LicenseData.String
+		}
+	}
+
+	if err := tmpl.Render(w, r, "admin_request.html", page); err != nil {
+		slog.ErrorContext(r.Context(), "Error rendering license request", "license_request_id", id, "error", err)
+	}
+}
+
+// ApproveLicenseRequestHandler одобряет заявку с указанным TAG
+func ApproveLicenseRequestHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.FormValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid request ID", http.StatusBadRequest)
+		return
+	}
+	tag, err := strconv.Atoi(r.FormValue("tag"))
+	if err != nil || tag < 1 || tag > 1000 {
+		http.Error(w, "Invalid TAG", http.StatusBadRequest)
+		return
+	}
+
+	if err := Approve(r.Context(), id, tag, adminauth.CurrentUser(r)); err != nil {
+		slog.ErrorContext(r.Context(), "Error approving license request", "license_request_id", id, "error", err)
+		http.Error(w, "Cannot approve license request: "+err.Error(), http.StatusConflict)
+		return
+	}
+	redirectBack(w, r, id)
+}
+
+// TransferLicenseHandler переносит одобренную лицензию на новый ключ/машину
+func TransferLicenseHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.FormValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid request ID", http.StatusBadRequest)
+		return
+	}
+	newKey := strings.TrimSpace(r.FormValue("new_license_key"))
+	if newKey == "" {
+		http.Error(w, "New license key is required", http.StatusBadRequest)
+		return
+	}
+
+	newID, err := Transfer(r.Context(), id, newKey, strings.TrimSpace(r.FormValue("new_fingerprint")), adminauth.CurrentUser(r))
+	if err == ErrKeyInUse {
+		http.Error(w, "New license key already has a pending or approved request", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error transferring license", "license_request_id", id, "error", err)
+		http.Error(w, "Cannot transfer license: "+err.Error(), http.StatusConflict)
+		return
+	}
+	slog.InfoContext(r.Context(), "License transferred", "license_request_id", id, "new_license_request_id", newID)
+	redirectBack(w, r, newID)
+}
+
+// ReleaseLicenseHandler освобождает активацию по решению администратора
+func ReleaseLicenseHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.FormValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid request ID", http.StatusBadRequest)
+		return
+	}
+	if err := Release(r.Context(), id, adminauth.CurrentUser(r)); err != nil {
+		slog.ErrorContext(r.Context(), "Error releasing license", "license_request_id", id, "error", err)
+		http.Error(w, "Cannot release license", http.StatusConflict)
+		return
+	}
+	redirectBack(w, r, id)
+}
+
+// AssignOrganizationHandler — выбор организации для заявки администратором
+func AssignOrganizationHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.FormValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid request ID", http.StatusBadRequest)
+		return
+	}
+	orgID := 0
+	if v := r.FormValue("organization_id"); v != "" {
+		if orgID, err = strconv.Atoi(v); err != nil {
+			http.Error(w, "Invalid organization ID", http.StatusBadRequest)
+			return
+		}
+	}
+	if err := AssignOrganization(id, orgID, adminauth.CurrentUser(r)); err != nil {
+		slog.ErrorContext(r.Context(), "Error assigning organization", "license_request_id", id, "error", err)
+		http.Error(w, "Cannot assign organization: "+err.Error(), http.StatusConflict)
+		return
+	}
+	redirectBack(w, r, id)
+}
+
+// RejectLicenseRequestHandler отклоняет заявку
+func RejectLicenseRequestHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.FormValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid request ID", http.StatusBadRequest)
+		return
+	}
+	if err := Reject(r.Context(), id, adminauth.CurrentUser(r)); err != nil {
+		slog.ErrorContext(r.Context(), "Error rejecting license request", "license_request_id", id, "error", err)
+		http.Error(w, "Cannot reject license request", http.StatusConflict)
+		return
+	}
+	redirectBack(w, r, id)
+}
+
+// redirectBack возвращает туда, откуда пришло действие: на страницу заявки id
+// (форма со страницы заявки передаёт view=request) или на список заявок,
+// сохраняя выбранный продукт
+func redirectBack(w http.ResponseWriter, r *http.Request, id int) {
+	if r.FormValue("view") == "request" {
+		http.Redirect(w, r, fmt.Sprintf("/admin/license-request?id=%d", id), http.StatusSeeOther)
+		return
+	}
+	target := "/admin/license-requests"
+	if product := r.FormValue("product"); product != "" {
+		target += "?" + url.Values{"product": {product}}.Encode()
+	}
+	http.Redirect(w, r, target, http.StatusSeeOther)
+}
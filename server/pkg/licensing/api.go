@@ -0,0 +1,440 @@
+package licensing
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"example.com/licence-approval/server/pkg/clientcert"
+	"example.com/licence-approval/server/pkg/i18n"
+	"example.com/licence-approval/server/pkg/orgs"
+	"example.com/licence-approval/server/pkg/security"
+)
+
+// Заголовки, которыми клиент сообщает, для какого продукта нужна лицензия
+const (
+	ProductHeader        = "X-License-Product"
+	ProductVersionHeader = "X-License-Product-Version"
+	// Организация: заявленный идентификатор или код приглашения
+	OrganizationHeader = "X-License-Organization"
+	InviteCodeHeader   = "X-License-Invite-Code"
+	// Отпечаток машины и запрос пробной лицензии
+	FingerprintHeader = "X-Machine-Fingerprint"
+	TrialHeader       = "X-License-Trial"
+	// Сведения о запрашивающем для правил автоматического решения
+	HostnameHeader       = "X-License-Hostname"
+	RequesterEmailHeader = "X-License-Requester-Email"
+)
+
+// PendingRetryAfter — через сколько клиенту стоит снова проверить заявку,
+// ожидающую решения (заголовок Retry-After)
+var PendingRetryAfter = 15 * time.Second
+
+// checkLicenseResponse — ответ check-license. Клиенты разбирают status и reason;
+// has_license и message сохранены для прежних клиентов.
+type checkLicenseResponse struct {
+	Status     string     `json:"status"`
+	Reason     string     `json:"reason,omitempty"`
+	RequestID  int        `json:"request_id,omitempty"`
+	HasLicense bool       `json:"has_license"`
+	Message    string     `json:"message"`
+	Product    string     `json:"product"`
+	Trial      bool       `json:"trial,omitempty"`
+	CreatedAt  *time.Time `json:"created_at,omitempty"`
+	DecidedAt  *time.Time `json:"decided_at,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	// Через сколько секунд проверить снова (дублирует заголовок Retry-After)
+	RetryAfter int    `json:"retry_after,omitempty"`
+	License    string `json:"license,omitempty"`
+	Signature  string `json:"signature,omitempty"`
+	// Клиентский сертификат для взаимного TLS (PEM)
+	ClientCertificate string `json:"client_certificate,omitempty"`
+}
+
+type createLicenseRequest struct {
+	LicenseKey     string `json:"license_key"`
+	Product        string `json:"product"`
+	ProductVersion string `json:"product_version"`
+	Organization   string `json:"organization"`
+	InviteCode     string `json:"invite_code"`
+	Fingerprint    string `json:"fingerprint"`
+	Trial          bool   `json:"trial"`
+	Hostname       string `json:"hostname"`
+	RequesterEmail string `json:"requester_email"`
+	// Сведения для администратора, принимающего решение
+	RequesterName string            `json:"requester_name"`
+	Justification string            `json:"justification"`
+	OS            string            `json:"os"`
+	Arch          string            `json:"arch"`
+	AppVersion    string            `json:"app_version"`
+	Metadata      map[string]string `json:"metadata"`
+}
+
+type createLicenseResponse struct {
+	RequestID int        `json:"request_id"`
+	Status    string     `json:"status,omitempty"`
+	Reason    string     `json:"reason,omitempty"`
+	Message   string     `json:"message"`
+	Trial     bool       `json:"trial,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// productFromRequest определяет продукт: поле тела, параметр запроса, заголовок, иначе продукт по умолчанию
+func productFromRequest(r *http.Request, fromBody string) string {
+	if v := firstNonEmpty(fromBody, r.URL.Query().Get("product"), r.Header.Get(ProductHeader)); v != "" {
+		return v
+	}
+	return DefaultProductCode
+}
+
+// CheckLicenseHandler — GET /api/check-license?license_key=...&product=...
+func CheckLicenseHandler(w http.ResponseWriter, r *http.Request) {
+	licenseKey := r.URL.Query().Get("license_key")
+	if licenseKey == "" {
+		writeProblem(w, r, http.StatusBadRequest, ProblemLicenseKeyRequired, "license_key is required")
+		return
+	}
+	productCode := productFromRequest(r, "")
+	product, err := GetProduct(r.Context(), productCode)
+	if err != nil {
+		writeProblem(w, r, http.StatusNotFound, ProblemUnknownProduct, "Unknown product "+productCode)
+		return
+	}
+
+	lang := i18n.APILanguage(r)
+	resp := checkLicenseResponse{Product: productCode}
+	lr, err := FindLatestRequest(r.Context(), licenseKey, productCode)
+	if (err == nil || err == ErrRequestNotFound) && !authorizeClient(w, r, licenseKey, lr) {
+		return
+	}
+	switch {
+	case err == ErrRequestNotFound:
+		resp.Status, resp.Reason, resp.Message = APIStatusNotActive, ReasonNoRequest, i18n.T(lang, "api.license_not_active")
+		writeJSON(w, http.StatusOK, resp)
+		return
+	case err != nil:
+		slog.ErrorContext(r.Context(), "Error checking license", "error", err)
+		writeProblem(w, r, http.StatusInternalServerError, ProblemInternal, "")
+		return
+	}
+
+	var msgKey string
+	resp.Status, resp.Reason, msgKey = requestStatus(lr, time.Now())
+	resp.Message = i18n.T(lang, msgKey)
+	resp.RequestID = lr.ID
+	// История проверок видна администратору на странице заявки
+	if err := RecordCheckIn(r.Context(), lr.ID, remoteIP(r), resp.Status); err != nil {
+		slog.WarnContext(r.Context(), "Error recording check-in", "license_request_id", lr.ID, "error", err)
+	}
+	resp.Trial = lr.IsTrial
+	resp.CreatedAt = &lr.CreatedAt
+	if lr.DecidedAt.Valid {
+		resp.DecidedAt = &lr.DecidedAt.Time
+	}
+	if lr.ExpiresAt.Valid {
+		resp.ExpiresAt = &lr.ExpiresAt.Time
+	}
+	switch resp.Status {
+	case APIStatusActive:
+		resp.HasLicense = true
+		resp.License, resp.Signature = checkedLicense(r.Context(), product, lr, time.Now())
+		resp.ClientCertificate = clientCertificate(r, lr)
+	case APIStatusPending:
+		resp.RetryAfter = int(PendingRetryAfter / time.Second)
+		w.Header().Set("Retry-After", strconv.Itoa(resp.RetryAfter))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// checkedLicense подписывает лицензию заявки заново, добавив время проверки now:
+// подписанное время не даёт клиенту продлить работу без сервера, поправив свой кэш.
+// Если подписать не удалось, отдаётся лицензия без времени проверки.
+func checkedLicense(ctx context.Context, product *Product, lr *LicenseRequest, now time.Time) (license, signature string) {
+	var lic License
+	if err := json.Unmarshal([]byte(lr.LicenseData.String), &lic); err != nil {
+		slog.ErrorContext(ctx, "Error parsing stored license", "license_request_id", lr.ID, "error", err)
+		return lr.LicenseData.String, lr.Signature.String
+	}
+	checkedAt := now.UTC()
+	lic.CheckedAt = &checkedAt
+	payload, err := json.Marshal(lic)
+	if err == nil {
+		signature, err = security.SignLicense(ctx, product.SigningKeyPath, payload)
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Error signing checked license", "license_request_id", lr.ID, "error", err)
+		return lr.LicenseData.String, lr.Signature.String
+	}
+	return string(payload), signature
+}
+
+// requestStatus — статус заявки для API, уточнение и ключ сообщения для человека
+// в каталоге i18n
+func requestStatus(lr *LicenseRequest, now time.Time) (status, reason, msgKey string) {
+	switch {
+	case lr.Status == StatusApproved && lr.Expired(now):
+		return APIStatusExpired, ReasonTrialExpired, "api.trial_expired"
+	case lr.Status == StatusApproved && lr.IsTrial:
+		return APIStatusActive, ReasonTrial, "api.license_active"
+	case lr.Status == StatusApproved:
+		return APIStatusActive, "", "api.license_active"
+	case lr.Status == StatusPending:
+		return APIStatusPending, ReasonAwaitingApproval, "api.request_pending"
+	case lr.Status == StatusRejected && strings.HasPrefix(lr.PolicyDecision, "reject"):
+		return APIStatusRejected, ReasonPolicy, "api.request_rejected"
+	case lr.Status == StatusRejected:
+		return APIStatusRejected, "", "api.request_rejected"
+	case lr.Status == StatusReleased:
+		return APIStatusDeactivated, "", "api.license_deactivated"
+	case lr.Status == StatusTransferred:
+		return APIStatusTransferred, "", "api.license_transferred"
+	}
+	return APIStatusNotActive, "", "api.license_not_active"
+}
+
+// CreateLicenseRequestHandler — POST /api/create-license-request
+func CreateLicenseRequestHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	var body createLicenseRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, ProblemBadRequest, "Invalid JSON body")
+		return
+	}
+	if body.LicenseKey == "" {
+		writeProblem(w, r, http.StatusBadRequest, ProblemLicenseKeyRequired, "license_key is required")
+		return
+	}
+	productCode := productFromRequest(r, body.Product)
+	version := firstNonEmpty(body.ProductVersion, r.Header.Get(ProductVersionHeader))
+
+	product, err := GetProduct(r.Context(), productCode)
+	if err == ErrProductNotFound {
+		writeProblem(w, r, http.StatusNotFound, ProblemUnknownProduct, "Unknown product "+productCode)
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error loading product", "product", productCode, "error", err)
+		writeProblem(w, r, http.StatusInternalServerError, ProblemInternal, "")
+		return
+	}
+	if !product.HasVersion(version) {
+		writeProblem(w, r, http.StatusBadRequest, ProblemUnknownVersion, ErrUnknownVersion.Error())
+		return
+	}
+
+	// Повторная заявка не нужна, пока предыдущая ожидает решения или действует
+	existing, err := FindLatestRequest(r.Context(), body.LicenseKey, productCode)
+	if err != nil && err != ErrRequestNotFound {
+		slog.ErrorContext(r.Context(), "Error looking up license request", "error", err)
+		writeProblem(w, r, http.StatusInternalServerError, ProblemInternal, "")
+		return
+	}
+	if !authorizeClient(w, r, body.LicenseKey, existing) {
+		return
+	}
+	csr, err := clientCSR(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, ProblemInvalidCSR, err.Error())
+		return
+	}
+	trial := body.Trial || isTrue(r.Header.Get(TrialHeader))
+	if existing != nil && (existing.Status == StatusPending || existing.Status == StatusApproved) {
+		// Запрос полной лицензии поверх пробной уходит администратору на перевод
+		if existing.IsTrial && !trial {
+			if err := RequestConversion(r.Context(), existing.ID); err != nil {
+				slog.WarnContext(r.Context(), "Error requesting trial conversion", "license_request_id", existing.ID, "error", err)
+			}
+		}
+		writeProblemFor(w, r, problem{
+			Status:    http.StatusConflict,
+			Code:      ProblemRequestExists,
+			Detail:    "License request already exists.",
+			RequestID: existing.ID,
+		})
+		return
+	}
+
+	// Организация: код приглашения обязан быть верным, неизвестный claim просто игнорируется —
+	// администратор сможет выбрать организацию вручную
+	inviteCode := firstNonEmpty(body.InviteCode, r.Header.Get(InviteCodeHeader))
+	orgID, err := orgs.Resolve(inviteCode, firstNonEmpty(body.Organization, r.Header.Get(OrganizationHeader)))
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error resolving organization", "error", err)
+		writeProblem(w, r, http.StatusInternalServerError, ProblemInternal, "")
+		return
+	}
+	if inviteCode != "" && orgID == 0 {
+		writeProblem(w, r, http.StatusBadRequest, ProblemUnknownInviteCode, "")
+		return
+	}
+
+	nr := NewRequest{
+		LicenseKey:     body.LicenseKey,
+		ProductCode:    productCode,
+		ProductVersion: version,
+		OrganizationID: orgID,
+		Fingerprint:    firstNonEmpty(body.Fingerprint, r.Header.Get(FingerprintHeader)),
+		RemoteIP:       remoteIP(r),
+		Hostname:       firstNonEmpty(body.Hostname, r.Header.Get(HostnameHeader)),
+		RequesterEmail: firstNonEmpty(body.RequesterEmail, r.Header.Get(RequesterEmailHeader)),
+		ClientCSR:      csr,
+		RequesterName:  body.RequesterName,
+		Justification:  body.Justification,
+		OS:             body.OS,
+		Arch:           body.Arch,
+		AppVersion:     body.AppVersion,
+		Metadata:       body.Metadata,
+		// Код приглашения проверяет сервер, claim — только слова клиента
+		OrganizationVerified: inviteCode != "" && orgID != 0,
+	}
+	if err := validateDetails(&nr); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, ProblemBadRequest, err.Error())
+		return
+	}
+
+	if trial {
+		createTrial(w, r, nr)
+		return
+	}
+
+	id, err := CreateRequest(r.Context(), nr)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error creating license request", "error", err)
+		writeProblem(w, r, http.StatusInternalServerError, ProblemInternal, "")
+		return
+	}
+
+	lang := i18n.APILanguage(r)
+	resp := createLicenseResponse{
+		RequestID: id,
+		Status:    APIStatusPending,
+		Reason:    ReasonAwaitingApproval,
+		Message:   i18n.T(lang, "api.request_created"),
+	}
+	status, err := applyPolicy(r.Context(), id)
+	if err != nil {
+		// Заявка уже создана — её решит администратор
+		slog.WarnContext(r.Context(), "Error applying policy", "license_request_id", id, "error", err)
+	}
+	switch status {
+	case StatusApproved:
+		resp.Status, resp.Reason, resp.Message = APIStatusActive, ReasonAutoApproved, i18n.T(lang, "api.request_auto_approved")
+	case StatusRejected:
+		resp.Status, resp.Reason, resp.Message = APIStatusRejected, ReasonPolicy, i18n.T(lang, "api.request_rejected")
+	}
+	writeJSON(w, http.StatusCreated, resp)
+}
+
+type deactivateRequest struct {
+	LicenseKey  string `json:"license_key"`
+	Product     string `json:"product"`
+	Fingerprint string `json:"fingerprint"`
+}
+
+// DeactivateLicenseHandler — POST /api/deactivate: клиент освобождает активацию
+func DeactivateLicenseHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	var body deactivateRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, ProblemBadRequest, "Invalid JSON body")
+		return
+	}
+	if body.LicenseKey == "" {
+		writeProblem(w, r, http.StatusBadRequest, ProblemLicenseKeyRequired, "license_key is required")
+		return
+	}
+	productCode := productFromRequest(r, body.Product)
+	fingerprint := firstNonEmpty(body.Fingerprint, r.Header.Get(FingerprintHeader))
+
+	lr, err := FindLatestRequest(r.Context(), body.LicenseKey, productCode)
+	if err != nil && err != ErrRequestNotFound {
+		slog.ErrorContext(r.Context(), "Error looking up license request", "error", err)
+		writeProblem(w, r, http.StatusInternalServerError, ProblemInternal, "")
+		return
+	}
+	if !authorizeClient(w, r, body.LicenseKey, lr) {
+		return
+	}
+
+	identified := clientcert.Identity(r) == body.LicenseKey
+	id, err := Deactivate(r.Context(), body.LicenseKey, productCode, fingerprint, identified)
+	switch err {
+	case nil:
+	case ErrNotActive:
+		writeProblem(w, r, http.StatusNotFound, ProblemNotActive, "")
+		return
+	case ErrFingerprintMismatch:
+		writeProblem(w, r, http.StatusForbidden, ProblemFingerprintMismatch, "")
+		return
+	case ErrIdentityRequired:
+		writeProblem(w, r, http.StatusForbidden, ProblemClientCertRequired, "")
+		return
+	default:
+		slog.ErrorContext(r.Context(), "Error deactivating license", "error", err)
+		writeProblem(w, r, http.StatusInternalServerError, ProblemInternal, "")
+		return
+	}
+	writeJSON(w, http.StatusOK, createLicenseResponse{
+		RequestID: id,
+		Status:    APIStatusDeactivated,
+		Message:   i18n.T(i18n.APILanguage(r), "api.license_deactivated"),
+	})
+}
+
+// createTrial выдаёт пробную лицензию без участия администратора
+func createTrial(w http.ResponseWriter, r *http.Request, nr NewRequest) {
+	if nr.Fingerprint == "" {
+		writeProblem(w, r, http.StatusBadRequest, ProblemFingerprintRequired, "Machine fingerprint is required for a trial")
+		return
+	}
+	id, err := CreateTrial(r.Context(), nr)
+	if err == ErrTrialUsed {
+		writeProblem(w, r, http.StatusForbidden, ProblemTrialUsed, "")
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error creating trial license", "error", err)
+		writeProblem(w, r, http.StatusInternalServerError, ProblemInternal, "")
+		return
+	}
+	lr, err := GetRequest(r.Context(), id)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error loading trial license", "license_request_id", id, "error", err)
+		writeProblem(w, r, http.StatusInternalServerError, ProblemInternal, "")
+		return
+	}
+	writeJSON(w, http.StatusCreated, createLicenseResponse{
+		RequestID: id,
+		Status:    APIStatusActive,
+		Reason:    ReasonTrial,
+		Message:   i18n.T(i18n.APILanguage(r), "api.trial_approved"),
+		Trial:     true,
+		ExpiresAt: &lr.ExpiresAt.Time,
+	})
+}
+
+func isTrue(v string) bool {
+	b, _ := strconv.ParseBool(v)
+	return b
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Warn("Error writing JSON response", "error", err)
+	}
+}
@@ -0,0 +1,211 @@
+package licensing
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"example.com/licence-approval/server/pkg/db"
+	"example.com/licence-approval/server/pkg/orgs"
+	"example.com/licence-approval/server/pkg/policy"
+	"example.com/licence-approval/server/pkg/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Действующая политика автоматического решения; nil — все заявки решаются вручную
+var (
+	activePolicy       *policy.Policy
+	activePolicySource string
+)
+
+// LoadPolicy загружает файл политики
+func LoadPolicy(filename string) error {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	p, err := policy.Parse(data)
+	if err != nil {
+		return err
+	}
+	activePolicy = p
+	activePolicySource = string(data)
+	return nil
+}
+
+// policyInput собирает атрибуты заявки для правил; организация передаётся,
+// только если она подтверждена (orgNames — названия по ID, см. orgs.Names)
+func policyInput(lr *LicenseRequest, orgNames map[int]string) policy.Input {
+	in := policy.Input{
+		Product:        lr.ProductCode,
+		RequesterEmail: lr.RequesterEmail,
+		Hostname:       lr.Hostname,
+		IP:             net.ParseIP(lr.RemoteIP),
+		At:             lr.CreatedAt,
+	}
+	if lr.OrganizationID.Valid && lr.OrganizationVerified {
+		in.Organization = orgNames[int(lr.OrganizationID.Int64)]
+	}
+	return in
+}
+
+// applyPolicy проверяет новую заявку по правилам и, если политика не в режиме
+// dry-run, сразу одобряет или отклоняет её. Возвращает итоговый статус заявки.
+func applyPolicy(ctx context.Context, id int) (_ string, err error) {
+	if activePolicy == nil {
+		return StatusPending, nil
+	}
+	ctx, span := tracing.Start(ctx, "licensing.applyPolicy")
+	defer func() { tracing.End(span, err) }()
+
+	lr, err := GetRequest(ctx, id)
+	if err != nil {
+		return "", err
+	}
+
+	orgNames, err := orgs.Names()
+	if err != nil {
+		return "", err
+	}
+	decision := activePolicy.Evaluate(policyInput(lr, orgNames))
+	record := decision.String()
+	if activePolicy.DryRun {
+		record = "dry-run: " + record
+	}
+	span.SetAttributes(attribute.String("policy.decision", record))
+	if _, err := db.DB.ExecContext(ctx, `UPDATE license_requests SET policy_decision = $1 WHERE id = $2`,
+		record, id); err != nil {
+		return "", err
+	}
+	if err := recordEvent(db.DB, id, EventPolicyDecision, lr.Status, lr.Status, ActorPolicy, record); err != nil {
+		return "", err
+	}
+	if activePolicy.DryRun {
+		return StatusPending, nil
+	}
+
+	switch decision.Action {
+	case policy.ActionApprove:
+		tag := decision.Tag
+		if tag == 0 {
+			product, err := GetProduct(ctx, lr.ProductCode)
+			if err != nil {
+				return "", err
+			}
+			tag = product.DefaultTag
+		}
+		// Например, квота организации исчерпана — оставляем заявку на ручное решение
+		if err := Approve(ctx, id, tag, ActorPolicy); err != nil {
+			slog.WarnContext(ctx, "Policy could not approve request", "license_request_id", id, "error", err)
+			return StatusPending, nil
+		}
+		return StatusApproved, nil
+	case policy.ActionReject:
+		if err := Reject(ctx, id, ActorPolicy); err != nil {
+			return "", err
+		}
+		return StatusRejected, nil
+	}
+	return StatusPending, nil
+}
+
+// remoteIP возвращает IP клиента из RemoteAddr
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// dryRunRow — заявка и решение, которое приняли бы правила
+type dryRunRow struct {
+	Request  LicenseRequest
+	Decision policy.Decision
+	// Решение правил отличается от фактического статуса
+	Differs bool
+}
+
+// policyPage — данные для admin_policy.html
+type policyPage struct {
+	Source string
+	Active bool
+	DryRun bool
+	Days   int
+	Error  string
+	Rows   []dryRunRow
+}
+
+// PolicyHandler показывает действующие правила и форму dry-run
+func PolicyHandler(w http.ResponseWriter, r *http.Request) {
+	page := policyPage{Source: activePolicySource, Active: activePolicy != nil, Days: 30}
+	if activePolicy != nil {
+		page.DryRun = activePolicy.DryRun
+	}
+	renderPolicy(w, r, page)
+}
+
+// PolicyDryRunHandler прогоняет правила из формы по заявкам за последние N дней, ничего не меняя
+func PolicyDryRunHandler(w http.ResponseWriter, r *http.Request) {
+	page := policyPage{Source: r.FormValue("rules"), Active: activePolicy != nil, Days: 30}
+	if activePolicy != nil {
+		page.DryRun = activePolicy.DryRun
+	}
+	if days, err := strconv.Atoi(r.FormValue("days")); err == nil && days > 0 && days <= 365 {
+		page.Days = days
+	}
+
+	p, err := policy.Parse([]byte(page.Source))
+	if err != nil {
+		page.Error = err.Error()
+		renderPolicy(w, r, page)
+		return
+	}
+	requests, err := ListRequestsSince(time.Now().AddDate(0, 0, -page.Days), 1000)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error listing requests for dry-run", "error", err)
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+	orgNames, err := orgs.Names()
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error listing organizations for dry-run", "error", err)
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+	for _, lr := range requests {
+		d := p.Evaluate(policyInput(&lr, orgNames))
+		page.Rows = append(page.Rows, dryRunRow{
+			Request:  lr,
+			Decision: d,
+			Differs:  !decisionMatchesStatus(d.Action, lr.Status),
+		})
+	}
+	renderPolicy(w, r, page)
+}
+
+func decisionMatchesStatus(a policy.Action, status string) bool {
+	switch a {
+	case policy.ActionApprove:
+		return status == StatusApproved
+	case policy.ActionReject:
+		return status == StatusRejected
+	}
+	return true
+}
+
+func renderPolicy(w http.ResponseWriter, r *http.Request, page policyPage) {
+	if page.Source == "" {
+		example, _ := json.MarshalIndent(policy.Policy{Default: policy.ActionManual, Rules: []policy.Rule{}}, "", "  ")
+		page.Source = string(example)
+	}
+	if err := tmpl.Render(w, r, "admin_policy.html", page); err != nil {
+		slog.ErrorContext(r.Context(), "Error rendering policy", "error", err)
+	}
+}
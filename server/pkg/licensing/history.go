@@ -0,0 +1,131 @@
+package licensing
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"example.com/licence-approval/server/pkg/db"
+	"example.com/licence-approval/server/pkg/tracing"
+)
+
+// Действия в истории заявки
+const (
+	EventCreated              = "created"
+	EventApproved             = "approved"
+	EventRejected             = "rejected"
+	EventReleased             = "released"
+	EventTransferred          = "transferred"
+	EventConversionRequested  = "conversion_requested"
+	EventOrganizationAssigned = "organization_assigned"
+	EventPolicyDecision       = "policy_decision"
+	EventCertificateIssued    = "certificate_issued"
+)
+
+// Исполнители действий, не являющиеся администраторами
+const (
+	ActorClient = "client"
+	ActorPolicy = "policy"
+	ActorSystem = "system"
+)
+
+// Event — запись истории заявки: кто, когда и что сделал
+type Event struct {
+	ID         int
+	RequestID  int
+	Action     string
+	FromStatus string
+	ToStatus   string
+	Actor      string
+	Detail     string
+	CreatedAt  time.Time
+}
+
+// CheckIn — проверки лицензии клиентом. Подряд идущие проверки с того же адреса
+// с тем же результатом сворачиваются в одну запись со счётчиком.
+type CheckIn struct {
+	RequestID int
+	RemoteIP  string
+	Status    string
+	Count     int
+	FirstSeen time.Time
+	LastSeen  time.Time
+}
+
+type execer interface {
+	Exec(string, ...interface{}) (sql.Result, error)
+}
+
+// recordEvent добавляет запись в историю заявки (в рамках транзакции, если q — *sql.Tx)
+func recordEvent(q execer, requestID int, action, from, to, actor, detail string) error {
+	_, err := q.Exec(`
+		INSERT INTO license_request_events (request_id, action, from_status, to_status, actor, detail)
+		VALUES ($1, $2, $3, $4, $5, $6)`, requestID, action, from, to, actor, detail)
+	return err
+}
+
+// ListEvents возвращает историю заявки в хронологическом порядке
+func ListEvents(requestID int) ([]Event, error) {
+	rows, err := db.DB.Query(`
+		SELECT id, request_id, action, from_status, to_status, actor, detail, created_at
+		FROM license_request_events WHERE request_id = $1
+		ORDER BY created_at, id`, requestID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []Event
+	for rows.Next() {
+		var e Event
+		if err := rows.Scan(&e.ID, &e.RequestID, &e.Action, &e.FromStatus, &e.ToStatus,
+			&e.Actor, &e.Detail, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, e)
+	}
+	return list, rows.Err()
+}
+
+// RecordCheckIn отмечает проверку лицензии клиентом
+func RecordCheckIn(ctx context.Context, requestID int, remoteIP, status string) (err error) {
+	ctx, span := tracing.StartDB(ctx, "RecordCheckIn")
+	defer func() { tracing.End(span, err) }()
+
+	res, err := db.DB.ExecContext(ctx, `
+		UPDATE license_checkins SET last_seen = NOW(), count = count + 1
+		WHERE id = (SELECT MAX(id) FROM license_checkins WHERE request_id = $1)
+			AND remote_ip = $2 AND status = $3`, requestID, remoteIP, status)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		return nil
+	}
+	_, err = db.DB.ExecContext(ctx, `
+		INSERT INTO license_checkins (request_id, remote_ip, status) VALUES ($1, $2, $3)`,
+		requestID, remoteIP, status)
+	return err
+}
+
+// ListCheckIns возвращает последние проверки лицензии, от новых к старым
+func ListCheckIns(requestID, limit int) ([]CheckIn, error) {
+	rows, err := db.DB.Query(`
+		SELECT request_id, remote_ip, status, count, first_seen, last_seen
+		FROM license_checkins WHERE request_id = $1
+		ORDER BY id DESC LIMIT $2`, requestID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []CheckIn
+	for rows.Next() {
+		var c CheckIn
+		if err := rows.Scan(&c.RequestID, &c.RemoteIP, &c.Status, &c.Count, &c.FirstSeen, &c.LastSeen); err != nil {
+			return nil, err
+		}
+		list = append(list, c)
+	}
+	return list, rows.Err()
+}
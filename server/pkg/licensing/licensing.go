@@ -0,0 +1,138 @@
+// Package licensing — заявки на лицензии в разрезе продуктов:
+// открытый API для клиентов и админские обработчики.
+package licensing
+
+import (
+	"example.com/licence-approval/server/config"
+	"example.com/licence-approval/server/pkg/db"
+	"example.com/licence-approval/server/pkg/logging"
+	"example.com/licence-approval/server/templates"
+)
+
+// Статусы заявки
+const (
+	StatusPending  = "pending"
+	StatusApproved = "approved"
+	StatusRejected = "rejected"
+	// Активация освобождена клиентом
+	StatusReleased = "released"
+	// Лицензия перенесена администратором на другой ключ
+	StatusTransferred = "transferred"
+)
+
+// DefaultProductCode — продукт, к которому относятся заявки без явного продукта
+const DefaultProductCode = "default"
+
+var tmpl *templates.Set
+
+// Init запоминает шаблоны админки и заводит продукт по умолчанию с основным ключом сервера
+func Init(cfg *config.Config, set *templates.Set) {
+	tmpl = set
+
+	_, err := db.DB.Exec(`
+		INSERT INTO products (code, name, signing_key_path)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (code) DO NOTHING`,
+		DefaultProductCode, "Default product", cfg.PrivateKeyPath)
+	if err != nil {
+		logging.Fatal("Error seeding default product", "error", err)
+	}
+}
+
+// Migrate создаёт таблицы каталога продуктов и добавляет продукт к заявкам
+func Migrate() {
+	stmts := []string{
+		`CREATE TABLE IF NOT EXISTS products (
+			code                 TEXT PRIMARY KEY,
+			name                 TEXT NOT NULL,
+			signing_key_path     TEXT NOT NULL,
+			default_tag          INTEGER NOT NULL DEFAULT 1,
+			default_entitlements TEXT NOT NULL DEFAULT '{}',
+			created_at           TIMESTAMP NOT NULL DEFAULT NOW()
+		)`,
+		`CREATE TABLE IF NOT EXISTS product_versions (
+			product_code TEXT NOT NULL REFERENCES products(code) ON DELETE CASCADE,
+			version      TEXT NOT NULL,
+			created_at   TIMESTAMP NOT NULL DEFAULT NOW(),
+			PRIMARY KEY (product_code, version)
+		)`,
+		`ALTER TABLE license_requests ADD COLUMN IF NOT EXISTS product_code TEXT NOT NULL DEFAULT 'default'`,
+		`ALTER TABLE license_requests ADD COLUMN IF NOT EXISTS product_version TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE license_requests ADD COLUMN IF NOT EXISTS tag INTEGER`,
+		`ALTER TABLE license_requests ADD COLUMN IF NOT EXISTS license_data TEXT`,
+		`ALTER TABLE license_requests ADD COLUMN IF NOT EXISTS signature TEXT`,
+		`ALTER TABLE license_requests ADD COLUMN IF NOT EXISTS decided_at TIMESTAMP`,
+		`CREATE INDEX IF NOT EXISTS license_requests_key_product_idx
+			ON license_requests (license_key, product_code)`,
+		// Пробные лицензии
+		`ALTER TABLE license_requests ADD COLUMN IF NOT EXISTS fingerprint TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE license_requests ADD COLUMN IF NOT EXISTS is_trial BOOLEAN NOT NULL DEFAULT FALSE`,
+		`ALTER TABLE license_requests ADD COLUMN IF NOT EXISTS conversion_requested BOOLEAN NOT NULL DEFAULT FALSE`,
+		`ALTER TABLE license_requests ADD COLUMN IF NOT EXISTS expires_at TIMESTAMP`,
+		// Атрибуты заявки для правил автоматического решения
+		`ALTER TABLE license_requests ADD COLUMN IF NOT EXISTS remote_ip TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE license_requests ADD COLUMN IF NOT EXISTS hostname TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE license_requests ADD COLUMN IF NOT EXISTS requester_email TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE license_requests ADD COLUMN IF NOT EXISTS policy_decision TEXT NOT NULL DEFAULT ''`,
+		// Перенос лицензий: origin_request_id связывает цепочку переносов одной лицензии
+		`ALTER TABLE license_requests ADD COLUMN IF NOT EXISTS origin_request_id INTEGER`,
+		// Офлайн-активация по файлу заявки
+		`ALTER TABLE license_requests ADD COLUMN IF NOT EXISTS offline_activation BOOLEAN NOT NULL DEFAULT FALSE`,
+		`ALTER TABLE license_requests ADD COLUMN IF NOT EXISTS installation_key TEXT NOT NULL DEFAULT ''`,
+		// Взаимный TLS: запрос на сертификат клиента и выпущенный сертификат
+		`ALTER TABLE license_requests ADD COLUMN IF NOT EXISTS client_csr TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE license_requests ADD COLUMN IF NOT EXISTS client_cert TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE license_requests ADD COLUMN IF NOT EXISTS client_cert_serial TEXT NOT NULL DEFAULT ''`,
+		// Сведения о запрашивающем и машине для администратора
+		`ALTER TABLE license_requests ADD COLUMN IF NOT EXISTS requester_name TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE license_requests ADD COLUMN IF NOT EXISTS justification TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE license_requests ADD COLUMN IF NOT EXISTS os TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE license_requests ADD COLUMN IF NOT EXISTS arch TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE license_requests ADD COLUMN IF NOT EXISTS app_version TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE license_requests ADD COLUMN IF NOT EXISTS metadata TEXT NOT NULL DEFAULT '{}'`,
+		`CREATE TABLE IF NOT EXISTS license_transfers (
+			id                SERIAL PRIMARY KEY,
+			origin_request_id INTEGER NOT NULL,
+			from_request_id   INTEGER NOT NULL REFERENCES license_requests(id) ON DELETE CASCADE,
+			to_request_id     INTEGER NOT NULL REFERENCES license_requests(id) ON DELETE CASCADE,
+			actor             TEXT NOT NULL DEFAULT '',
+			created_at        TIMESTAMP NOT NULL DEFAULT NOW()
+		)`,
+		// История заявки и проверки лицензии клиентом
+		`CREATE TABLE IF NOT EXISTS license_request_events (
+			id          SERIAL PRIMARY KEY,
+			request_id  INTEGER NOT NULL REFERENCES license_requests(id) ON DELETE CASCADE,
+			action      TEXT NOT NULL,
+			from_status TEXT NOT NULL DEFAULT '',
+			to_status   TEXT NOT NULL DEFAULT '',
+			actor       TEXT NOT NULL DEFAULT '',
+			detail      TEXT NOT NULL DEFAULT '',
+			created_at  TIMESTAMP NOT NULL DEFAULT NOW()
+		)`,
+		`CREATE INDEX IF NOT EXISTS license_request_events_request_idx
+			ON license_request_events (request_id)`,
+		`CREATE TABLE IF NOT EXISTS license_checkins (
+			id         SERIAL PRIMARY KEY,
+			request_id INTEGER NOT NULL REFERENCES license_requests(id) ON DELETE CASCADE,
+			remote_ip  TEXT NOT NULL DEFAULT '',
+			status     TEXT NOT NULL,
+			count      INTEGER NOT NULL DEFAULT 1,
+			first_seen TIMESTAMP NOT NULL DEFAULT NOW(),
+			last_seen  TIMESTAMP NOT NULL DEFAULT NOW()
+		)`,
+		`CREATE INDEX IF NOT EXISTS license_checkins_request_idx
+			ON license_checkins (request_id, id)`,
+		`CREATE TABLE IF NOT EXISTS trial_fingerprints (
+			fingerprint  TEXT NOT NULL,
+			product_code TEXT NOT NULL REFERENCES products(code) ON DELETE CASCADE,
+			request_id   INTEGER NOT NULL REFERENCES license_requests(id) ON DELETE CASCADE,
+			created_at   TIMESTAMP NOT NULL DEFAULT NOW(),
+			PRIMARY KEY (fingerprint, product_code)
+		)`,
+	}
+	for _, stmt := range stmts {
+		if _, err := db.DB.Exec(stmt); err != nil {
+			logging.Fatal("Error migrating licensing schema", "error", err)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package licensing
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Ограничения на сведения о заявке, которые присылает клиент
+const (
+	maxFieldLen         = 256
+	maxJustificationLen = 4000
+	maxMetadataKeys     = 32
+	maxMetadataKeyLen   = 64
+	maxMetadataValueLen = 1024
+)
+
+// validateDetails обрезает пробелы в сведениях о заявке и проверяет их размер
+func validateDetails(nr *NewRequest) error {
+	fields := []struct {
+		name string
+		dst  *string
+		max  int
+	}{
+		{"requester_name", &nr.RequesterName, maxFieldLen},
+		{"requester_email", &nr.RequesterEmail, maxFieldLen},
+		{"hostname", &nr.Hostname, maxFieldLen},
+		{"os", &nr.OS, maxFieldLen},
+		{"arch", &nr.Arch, maxFieldLen},
+		{"app_version", &nr.AppVersion, maxFieldLen},
+		{"justification", &nr.Justification, maxJustificationLen},
+	}
+	for _, f := range fields {
+		*f.dst = strings.TrimSpace(*f.dst)
+		if utf8.RuneCountInString(*f.dst) > f.max {
+			return fmt.Errorf("%s is longer than %d characters", f.name, f.max)
+		}
+	}
+
+	if len(nr.Metadata) > maxMetadataKeys {
+		return fmt.Errorf("metadata has more than %d keys", maxMetadataKeys)
+	}
+	for k, v := range nr.Metadata {
+		if strings.TrimSpace(k) == "" {
+			return errors.New("metadata key must not be empty")
+		}
+		if utf8.RuneCountInString(k) > maxMetadataKeyLen {
+			return fmt.Errorf("metadata key %q is longer than %d characters", k, maxMetadataKeyLen)
+		}
+		if utf8.RuneCountInString(v) > maxMetadataValueLen {
+			return fmt.Errorf("metadata value of %q is longer than %d characters", k, maxMetadataValueLen)
+		}
+	}
+	return nil
+}
+
+// encodeMetadata сохраняет метаданные заявки как JSON-объект
+func encodeMetadata(m map[string]string) string {
+	if len(m) == 0 {
+		return "{}"
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		return "{}"
+	}
+	return string(data)
+}
+
+// MetadataFields разбирает metadata заявки в map
+func (lr *LicenseRequest) MetadataFields() map[string]string {
+	m := make(map[string]string)
+	if err := json.Unmarshal([]byte(lr.Metadata), &m); err != nil {
+		return map[string]string{}
+	}
+	return m
+}
+
+// Platform — ОС и архитектура машины клиента одной строкой ("linux/amd64")
+func (lr *LicenseRequest) Platform() string {
+	switch {
+	case lr.OS != "" && lr.Arch != "":
+		return lr.OS + "/" + lr.Arch
+	case lr.OS != "":
+		return lr.OS
+	}
+	return lr.Arch
+}
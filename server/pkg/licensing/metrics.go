@@ -0,0 +1,72 @@
+package licensing
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"example.com/licence-approval/server/pkg/db"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// collectTimeout ограничивает запросы к БД при сборе метрик: медленная БД
+// не должна подвешивать Prometheus
+const collectTimeout = 5 * time.Second
+
+var (
+	requestsDesc = prometheus.NewDesc("license_server_license_requests",
+		"License requests by status and product.", []string{"status", "product"}, nil)
+	pendingAgeDesc = prometheus.NewDesc("license_server_pending_oldest_age_seconds",
+		"Age of the oldest license request awaiting a decision.", nil, nil)
+)
+
+// statsCollector отдаёт число заявок по статусам и возраст очереди, читая их
+// из БД в момент сбора метрик
+type statsCollector struct{}
+
+// NewCollector — коллектор Prometheus со статистикой заявок
+func NewCollector() prometheus.Collector {
+	return statsCollector{}
+}
+
+func (statsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- requestsDesc
+	ch <- pendingAgeDesc
+}
+
+func (statsCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), collectTimeout)
+	defer cancel()
+
+	rows, err := db.DB.QueryContext(ctx, `
+		SELECT status, product_code, COUNT(*) FROM license_requests GROUP BY status, product_code`)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error collecting license request metrics", "error", err)
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var status, product string
+		var n int
+		if err := rows.Scan(&status, &product, &n); err != nil {
+			slog.ErrorContext(ctx, "Error collecting license request metrics", "error", err)
+			return
+		}
+		ch <- prometheus.MustNewConstMetric(requestsDesc, prometheus.GaugeValue, float64(n), status, product)
+	}
+	if err := rows.Err(); err != nil {
+		slog.ErrorContext(ctx, "Error collecting license request metrics", "error", err)
+		return
+	}
+
+	var age float64
+	err = db.DB.QueryRowContext(ctx, `
+		SELECT COALESCE(EXTRACT(EPOCH FROM NOW() - MIN(created_at)), 0)
+		FROM license_requests WHERE status = $1`, StatusPending).Scan(&age)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error collecting pending queue metrics", "error", err)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(pendingAgeDesc, prometheus.GaugeValue, age)
+}
@@ -0,0 +1,107 @@
+package licensing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"example.com/licence-approval/server/pkg/clientcert"
+	"example.com/licence-approval/server/pkg/db"
+)
+
+// ClientCSRHeader — запрос клиента на сертификат для взаимного TLS (DER в base64)
+const ClientCSRHeader = "X-License-Client-CSR"
+
+var errCertificateReplaced = errors.New("client certificate was replaced concurrently")
+
+// issueClientCert выпускает сертификат по CSR заявки и сохраняет его. Сертификат
+// заменяется, только если с момента чтения заявки его не заменил другой запрос.
+func issueClientCert(ctx context.Context, q execer, lr *LicenseRequest) error {
+	certPEM, serial, err := clientcert.Issue(ctx, lr.ClientCSR, lr.LicenseKey)
+	if err != nil {
+		return fmt.Errorf("issue client certificate: %w", err)
+	}
+	res, err := q.Exec(`
+		UPDATE license_requests SET client_csr = $1, client_cert = $2, client_cert_serial = $3
+		WHERE id = $4 AND client_cert_serial = $5`, lr.ClientCSR, certPEM, serial, lr.ID, lr.ClientCertSerial)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return errCertificateReplaced
+	}
+	if err := recordEvent(q, lr.ID, EventCertificateIssued, lr.Status, lr.Status, ActorSystem, "serial "+serial); err != nil {
+		return err
+	}
+	lr.ClientCert, lr.ClientCertSerial = certPEM, serial
+	slog.InfoContext(ctx, "Client certificate issued", "license_request_id", lr.ID, "serial", serial)
+	return nil
+}
+
+// clientCertificate возвращает сертификат одобренной заявки для ответа клиенту.
+// Первый сертификат выпускается здесь, если при одобрении его не удалось выпустить,
+// и только на ключ из CSR, присланного с заявкой: администратор одобрял именно его.
+// Новый (скоро истекает, сменился ключ) — только клиенту, который установил
+// соединение с действующим сертификатом этой заявки: иначе знающий ключ лицензии
+// получил бы сертификат на свою пару ключей.
+func clientCertificate(r *http.Request, lr *LicenseRequest) string {
+	if !clientcert.Enabled() {
+		return ""
+	}
+	csr := r.Header.Get(ClientCSRHeader)
+	if csr == "" || !clientcert.CanIssue() || !clientcert.NeedsReissue(lr.ClientCert, csr, time.Now()) {
+		return lr.ClientCert
+	}
+	if lr.Fingerprint != "" && lr.Fingerprint != r.Header.Get(FingerprintHeader) {
+		return lr.ClientCert
+	}
+	if lr.ClientCert == "" && !clientcert.SameKey(lr.ClientCSR, csr) {
+		slog.WarnContext(r.Context(), "First client certificate for a key other than in the request refused",
+			"license_request_id", lr.ID)
+		return ""
+	}
+	if lr.ClientCert != "" && (lr.ClientCertSerial == "" || clientcert.Serial(r) != lr.ClientCertSerial) {
+		slog.WarnContext(r.Context(), "Client certificate reissue without the current certificate refused",
+			"license_request_id", lr.ID)
+		return lr.ClientCert
+	}
+	lr.ClientCSR = csr
+	if err := issueClientCert(r.Context(), db.DB, lr); err != nil {
+		slog.ErrorContext(r.Context(), "Error issuing client certificate", "license_request_id", lr.ID, "error", err)
+	}
+	return lr.ClientCert
+}
+
+// clientCSR — CSR из запроса на создание заявки; пусто, если сервер не выпускает сертификаты
+func clientCSR(r *http.Request) (string, error) {
+	csr := r.Header.Get(ClientCSRHeader)
+	if csr == "" || !clientcert.CanIssue() {
+		return "", nil
+	}
+	if _, err := clientcert.ParseCSR(csr); err != nil {
+		return "", err
+	}
+	return csr, nil
+}
+
+// authorizeClient проверяет клиентский сертификат для ключа licenseKey и при
+// ошибке отвечает 403. lr — последняя заявка ключа (nil, если её нет).
+func authorizeClient(w http.ResponseWriter, r *http.Request, licenseKey string, lr *LicenseRequest) bool {
+	issued := lr != nil && lr.ClientCert != ""
+	switch err := clientcert.Authorize(r, licenseKey, issued); err {
+	case nil:
+		return true
+	case clientcert.ErrCertificateRequired:
+		writeProblem(w, r, http.StatusForbidden, ProblemClientCertRequired, "")
+	default:
+		writeProblem(w, r, http.StatusForbidden, ProblemClientCertMismatch, "")
+	}
+	return false
+}
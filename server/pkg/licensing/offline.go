@@ -0,0 +1,263 @@
+package licensing
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"example.com/licence-approval/server/pkg/db"
+	"example.com/licence-approval/server/pkg/orgs"
+)
+
+// Форматы файлов офлайн-активации
+const (
+	ActivationRequestFormat  = "licence-approval/activation-request/v1"
+	ActivationResponseFormat = "licence-approval/activation-response/v1"
+)
+
+// Файл заявки не бывает большим
+const maxActivationRequestSize = 64 << 10
+
+var (
+	ErrBadActivationRequest = errors.New("activation request signature is invalid")
+	ErrInstallationMismatch = errors.New("activation request is signed by another installation of this license key")
+)
+
+// activationRequestFile — файл заявки, который клиент выгружает на машине без сети.
+// Request подписан ключом установки клиента (Ed25519). Ключ запоминается в заявке
+// при первой активации; пока заявка действует, файлы того же ключа лицензии
+// принимаются только с подписью этой установки.
+type activationRequestFile struct {
+	Format    string `json:"format"`
+	Request   string `json:"request"`
+	PublicKey string `json:"public_key"`
+	Signature string `json:"signature"`
+}
+
+// activationRequest — содержимое подписанной заявки
+type activationRequest struct {
+	LicenseKey     string    `json:"license_key"`
+	Fingerprint    string    `json:"fingerprint"`
+	Hostname       string    `json:"hostname"`
+	Product        string    `json:"product"`
+	ProductVersion string    `json:"product_version"`
+	Organization   string    `json:"organization"`
+	InviteCode     string    `json:"invite_code"`
+	RequesterEmail string    `json:"requester_email"`
+	CreatedAt      time.Time `json:"created_at"`
+
+	RequesterName string            `json:"requester_name,omitempty"`
+	Justification string            `json:"justification,omitempty"`
+	OS            string            `json:"os,omitempty"`
+	Arch          string            `json:"arch,omitempty"`
+	AppVersion    string            `json:"app_version,omitempty"`
+	Metadata      map[string]string `json:"metadata,omitempty"`
+
+	// Открытый ключ, которым подписан файл (base64); в подписанные данные не входит
+	installationKey string
+}
+
+// activationResponseFile — ответ администратора; клиент проверяет подпись ключом продукта
+type activationResponseFile struct {
+	Format    string `json:"format"`
+	RequestID int    `json:"request_id"`
+	License   string `json:"license"`
+	Signature string `json:"signature"`
+}
+
+// parseActivationRequest проверяет подпись файла заявки и разбирает его
+func parseActivationRequest(data []byte) (*activationRequest, error) {
+	var f activationRequestFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("parse activation request: %w", err)
+	}
+	if f.Format != ActivationRequestFormat {
+		return nil, fmt.Errorf("unsupported activation request format %q", f.Format)
+	}
+	pub, err := base64.StdEncoding.DecodeString(f.PublicKey)
+	if err != nil || len(pub) != ed25519.PublicKeySize {
+		return nil, ErrBadActivationRequest
+	}
+	sig, err := base64.StdEncoding.DecodeString(f.Signature)
+	if err != nil || !ed25519.Verify(ed25519.PublicKey(pub), []byte(f.Request), sig) {
+		return nil, ErrBadActivationRequest
+	}
+
+	var req activationRequest
+	if err := json.Unmarshal([]byte(f.Request), &req); err != nil {
+		return nil, fmt.Errorf("parse activation request: %w", err)
+	}
+	if req.LicenseKey == "" || req.Fingerprint == "" {
+		return nil, errors.New("activation request has no license key or fingerprint")
+	}
+	if req.Product == "" {
+		req.Product = DefaultProductCode
+	}
+	req.installationKey = base64.StdEncoding.EncodeToString(pub)
+	return &req, nil
+}
+
+// offlineRequest находит или заводит заявку по файлу офлайн-активации
+func offlineRequest(ctx context.Context, req *activationRequest) (*LicenseRequest, error) {
+	product, err := GetProduct(ctx, req.Product)
+	if err != nil {
+		return nil, fmt.Errorf("product %s: %w", req.Product, err)
+	}
+	if !product.HasVersion(req.ProductVersion) {
+		return nil, ErrUnknownVersion
+	}
+
+	// Повторная загрузка того же файла не должна плодить заявки
+	existing, err := FindLatestRequest(ctx, req.LicenseKey, product.Code)
+	if err != nil && err != ErrRequestNotFound {
+		return nil, err
+	}
+	if existing != nil &&
+		(existing.Status == StatusPending || existing.Status == StatusApproved || existing.Status == StatusRejected) {
+		// Действующая заявка уже привязана к установке — чужой файл не принимается
+		if existing.InstallationKey != "" && existing.InstallationKey != req.installationKey {
+			return nil, ErrInstallationMismatch
+		}
+		if existing.Fingerprint == req.Fingerprint {
+			if err := bindInstallationKey(ctx, existing.ID, req.installationKey); err != nil {
+				return nil, err
+			}
+			return existing, nil
+		}
+	}
+
+	orgID, err := orgs.Resolve(req.InviteCode, req.Organization)
+	if err != nil {
+		return nil, err
+	}
+	nr := NewRequest{
+		LicenseKey:      req.LicenseKey,
+		ProductCode:     product.Code,
+		ProductVersion:  req.ProductVersion,
+		OrganizationID:  orgID,
+		Fingerprint:     req.Fingerprint,
+		Hostname:        req.Hostname,
+		RequesterEmail:  req.RequesterEmail,
+		Offline:         true,
+		InstallationKey: req.installationKey,
+		RequesterName:   req.RequesterName,
+		Justification:   req.Justification,
+		OS:              req.OS,
+		Arch:            req.Arch,
+		AppVersion:      req.AppVersion,
+		Metadata:        req.Metadata,
+		// Код приглашения проверяет сервер, claim — только слова клиента
+		OrganizationVerified: strings.TrimSpace(req.InviteCode) != "" && orgID != 0,
+	}
+	if err := validateDetails(&nr); err != nil {
+		return nil, err
+	}
+	id, err := CreateRequest(ctx, nr)
+	if err != nil {
+		return nil, err
+	}
+	return GetRequest(ctx, id)
+}
+
+// bindInstallationKey запоминает ключ установки в заявке, созданной без него
+// (например, онлайн); заявка с другим ключом не меняется
+func bindInstallationKey(ctx context.Context, id int, key string) error {
+	res, err := db.DB.ExecContext(ctx, `
+		UPDATE license_requests SET installation_key = $1
+		WHERE id = $2 AND installation_key IN ('', $1)`, key, id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrInstallationMismatch
+	}
+	return nil
+}
+
+// OfflineActivationHandler показывает форму загрузки файла заявки
+func OfflineActivationHandler(w http.ResponseWriter, r *http.Request) {
+	if err := tmpl.Render(w, r, "admin_offline.html", nil); err != nil {
+		slog.ErrorContext(r.Context(), "Error rendering offline activation", "error", err)
+	}
+}
+
+// OfflineActivationUploadHandler принимает файл заявки, регистрирует её и ведёт
+// на страницу заявки: одобряет администратор там же отдельным действием, после
+// чего скачивает файл ответа. Повторная загрузка не меняет решение по заявке.
+func OfflineActivationUploadHandler(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxActivationRequestSize+4096)
+	file, _, err := r.FormFile("request_file")
+	if err != nil {
+		http.Error(w, "Activation request file is required", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, maxActivationRequestSize))
+	if err != nil {
+		http.Error(w, "Cannot read activation request file", http.StatusBadRequest)
+		return
+	}
+
+	req, err := parseActivationRequest(data)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	lr, err := offlineRequest(r.Context(), req)
+	if err != nil {
+		slog.WarnContext(r.Context(), "Error registering offline activation", "license_key", req.LicenseKey, "error", err)
+		http.Error(w, "Cannot register activation request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "Offline activation registered", "license_request_id", lr.ID)
+	http.Redirect(w, r, fmt.Sprintf("/admin/license-request?id=%d", lr.ID), http.StatusSeeOther)
+}
+
+// LicenseResponseHandler — повторная выгрузка файла ответа для одобренной заявки
+func LicenseResponseHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.FormValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid request ID", http.StatusBadRequest)
+		return
+	}
+	writeActivationResponse(w, r, id)
+}
+
+func writeActivationResponse(w http.ResponseWriter, r *http.Request, id int) {
+	lr, err := GetRequest(r.Context(), id)
+	if err == ErrRequestNotFound {
+		http.Error(w, "License request not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error loading license request", "license_request_id", id, "error", err)
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+	if lr.Status != StatusApproved || !lr.LicenseData.Valid {
+		http.Error(w, "License request is not approved", http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="license-response-%d.json"`, id))
+	writeJSON(w, http.StatusOK, activationResponseFile{
+		Format:    ActivationResponseFormat,
+		RequestID: lr.ID,
+		License:   lr.LicenseData.String,
+		Signature: lr.Signature.String,
+	})
+}
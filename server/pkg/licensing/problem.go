@@ -0,0 +1,115 @@
+package licensing
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"example.com/licence-approval/server/pkg/i18n"
+)
+
+// Статусы лицензии в ответах API (поле status) — их разбирают клиенты,
+// в отличие от поля message, предназначенного человеку
+const (
+	APIStatusActive      = "active"
+	APIStatusPending     = "pending"
+	APIStatusRejected    = "rejected"
+	APIStatusNotActive   = "not_active"
+	APIStatusExpired     = "expired"
+	APIStatusDeactivated = "deactivated"
+	APIStatusTransferred = "transferred"
+)
+
+// Уточнения статуса (поле reason)
+const (
+	ReasonTrial            = "trial"
+	ReasonTrialExpired     = "trial_expired"
+	ReasonAwaitingApproval = "awaiting_approval"
+	ReasonPolicy           = "policy"
+	ReasonNoRequest        = "no_request"
+	ReasonAutoApproved     = "auto_approved"
+)
+
+// Коды ошибок API (поле code в problem+json)
+const (
+	ProblemBadRequest          = "bad_request"
+	ProblemLicenseKeyRequired  = "license_key_required"
+	ProblemUnknownProduct      = "unknown_product"
+	ProblemUnknownVersion      = "unknown_version"
+	ProblemUnknownInviteCode   = "unknown_invite_code"
+	ProblemRequestExists       = "request_exists"
+	ProblemFingerprintRequired = "fingerprint_required"
+	ProblemTrialUsed           = "trial_used"
+	ProblemNotActive           = "not_active"
+	ProblemFingerprintMismatch = "fingerprint_mismatch"
+	ProblemInvalidCSR          = "invalid_csr"
+	ProblemClientCertRequired  = "client_certificate_required"
+	ProblemClientCertMismatch  = "client_certificate_mismatch"
+	ProblemNotFound            = "not_found"
+	ProblemMethodNotAllowed    = "method_not_allowed"
+	ProblemInternal            = "internal_error"
+)
+
+const (
+	problemTypePrefix  = "urn:licence-approval:problem:"
+	problemContentType = "application/problem+json"
+)
+
+// problem — ошибка API в формате RFC 7807 с расширениями code и request_id
+type problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	Instance  string `json:"instance,omitempty"`
+	Code      string `json:"code"`
+	RequestID int    `json:"request_id,omitempty"`
+}
+
+// writeProblem отвечает ошибкой API; detail — подробности для человека (может быть пустым)
+func writeProblem(w http.ResponseWriter, r *http.Request, status int, code, detail string) {
+	writeProblemFor(w, r, problem{Status: status, Code: code, Detail: detail})
+}
+
+func writeProblemFor(w http.ResponseWriter, r *http.Request, p problem) {
+	p.Type = problemTypePrefix + p.Code
+	// Заголовок — на языке клиента (Accept-Language), code остаётся неизменным
+	lang := i18n.APILanguage(r)
+	title, ok := i18n.Lookup(lang, "problem."+p.Code)
+	if !ok {
+		title = http.StatusText(p.Status)
+	}
+	p.Title = title
+	p.Instance = r.URL.Path
+
+	w.Header().Set("Content-Type", problemContentType)
+	w.Header().Set("Content-Language", lang)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+	if err := json.NewEncoder(w).Encode(p); err != nil {
+		slog.WarnContext(r.Context(), "Error writing problem response", "error", err)
+	}
+}
+
+// NotFoundHandler отвечает problem+json для /api/* и обычной 404 для остальных путей
+func NotFoundHandler(w http.ResponseWriter, r *http.Request) {
+	if isAPI(r) {
+		writeProblem(w, r, http.StatusNotFound, ProblemNotFound, "")
+		return
+	}
+	http.NotFound(w, r)
+}
+
+// MethodNotAllowedHandler — как NotFoundHandler, для неподдерживаемого метода
+func MethodNotAllowedHandler(w http.ResponseWriter, r *http.Request) {
+	if isAPI(r) {
+		writeProblem(w, r, http.StatusMethodNotAllowed, ProblemMethodNotAllowed, "")
+		return
+	}
+	http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+}
+
+func isAPI(r *http.Request) bool {
+	return strings.HasPrefix(r.URL.Path, "/api/")
+}
@@ -0,0 +1,257 @@
+package licensing
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"example.com/licence-approval/server/pkg/db"
+	"example.com/licence-approval/server/pkg/security"
+	"example.com/licence-approval/server/pkg/tracing"
+)
+
+// Product — позиция каталога продуктов
+type Product struct {
+	Code                string
+	Name                string
+	SigningKeyPath      string
+	DefaultTag          int
+	DefaultEntitlements string // JSON-объект
+	Versions            []string
+	CreatedAt           time.Time
+}
+
+var (
+	ErrProductNotFound = errors.New("product not found")
+	ErrUnknownVersion  = errors.New("unknown product version")
+
+	productCodeRe = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+)
+
+// GetProduct возвращает продукт по коду вместе со списком версий
+func GetProduct(ctx context.Context, code string) (_ *Product, err error) {
+	ctx, span := tracing.StartDB(ctx, "GetProduct")
+	defer func() { tracing.End(span, err, ErrProductNotFound) }()
+
+	var p Product
+	err = db.DB.QueryRowContext(ctx, `
+		SELECT code, name, signing_key_path, default_tag, default_entitlements, created_at
+		FROM products WHERE code = $1`, code).
+		Scan(&p.Code, &p.Name, &p.SigningKeyPath, &p.DefaultTag, &p.DefaultEntitlements, &p.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrProductNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	p.Versions, err = productVersions(code)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// CheckSigningKeys проверяет, что ключи подписи всех продуктов читаются —
+// для /readyz: без ключа одобренную заявку не подписать
+func CheckSigningKeys(ctx context.Context) error {
+	rows, err := db.DB.QueryContext(ctx, `SELECT code, signing_key_path FROM products ORDER BY code`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var code, path string
+		if err := rows.Scan(&code, &path); err != nil {
+			return err
+		}
+		if _, err := security.LoadProductKey(path); err != nil {
+			return fmt.Errorf("product %s: %w", code, err)
+		}
+	}
+	return rows.Err()
+}
+
+// ListProducts возвращает весь каталог, отсортированный по коду
+func ListProducts() ([]Product, error) {
+	rows, err := db.DB.Query(`
+		SELECT code, name, signing_key_path, default_tag, default_entitlements, created_at
+		FROM products ORDER BY code`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var products []Product
+	for rows.Next() {
+		var p Product
+		if err := rows.Scan(&p.Code, &p.Name, &p.SigningKeyPath, &p.DefaultTag,
+			&p.DefaultEntitlements, &p.CreatedAt); err != nil {
+			return nil, err
+		}
+		products = append(products, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for i := range products {
+		if products[i].Versions, err = productVersions(products[i].Code); err != nil {
+			return nil, err
+		}
+	}
+	return products, nil
+}
+
+func productVersions(code string) ([]string, error) {
+	rows, err := db.DB.Query(`
+		SELECT version FROM product_versions WHERE product_code = $1 ORDER BY created_at`, code)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var versions []string
+	for rows.Next() {
+		var v string
+		if err := rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		versions = append(versions, v)
+	}
+	return versions, rows.Err()
+}
+
+// HasVersion — известна ли версия продукту. Пустая версия допустима всегда.
+func (p *Product) HasVersion(version string) bool {
+	if version == "" {
+		return true
+	}
+	for _, v := range p.Versions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
+// Entitlements разбирает default_entitlements в map
+func (p *Product) Entitlements() map[string]interface{} {
+	ent := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(p.DefaultEntitlements), &ent); err != nil {
+		slog.Warn("Invalid default entitlements", "product", p.Code, "error", err)
+	}
+	return ent
+}
+
+// ProductPublicKeyHandler — GET /api/public-key?product=...: открытый ключ продукта
+// (PEM) для проверки лицензий на клиенте (параметр public_key клиента)
+func ProductPublicKeyHandler(w http.ResponseWriter, r *http.Request) {
+	productCode := productFromRequest(r, "")
+	product, err := GetProduct(r.Context(), productCode)
+	if err != nil {
+		writeProblem(w, r, http.StatusNotFound, ProblemUnknownProduct, "Unknown product "+productCode)
+		return
+	}
+	pub, err := security.ProductPublicKeyPEM(product.SigningKeyPath)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error loading product key", "product", productCode, "error", err)
+		writeProblem(w, r, http.StatusInternalServerError, ProblemInternal, "")
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-pem-file")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-public.pem"`, productCode))
+	w.Write(pub)
+}
+
+// ProductsHandler показывает каталог продуктов
+func ProductsHandler(w http.ResponseWriter, r *http.Request) {
+	products, err := ListProducts()
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error listing products", "error", err)
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+	if err := tmpl.Render(w, r, "admin_products.html", products); err != nil {
+		slog.ErrorContext(r.Context(), "Error rendering products", "error", err)
+	}
+}
+
+// CreateProductHandler добавляет продукт в каталог
+func CreateProductHandler(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+	code := strings.TrimSpace(r.FormValue("code"))
+	name := strings.TrimSpace(r.FormValue("name"))
+	keyPath := strings.TrimSpace(r.FormValue("signing_key_path"))
+	entitlements := strings.TrimSpace(r.FormValue("default_entitlements"))
+	if entitlements == "" {
+		entitlements = "{}"
+	}
+
+	if !productCodeRe.MatchString(code) || name == "" || keyPath == "" {
+		http.Error(w, "Invalid product code, name or signing key path", http.StatusBadRequest)
+		return
+	}
+	defaultTag, err := strconv.Atoi(r.FormValue("default_tag"))
+	if err != nil || defaultTag < 1 || defaultTag > 1000 {
+		http.Error(w, "Invalid default TAG", http.StatusBadRequest)
+		return
+	}
+	var ent map[string]interface{}
+	if err := json.Unmarshal([]byte(entitlements), &ent); err != nil {
+		http.Error(w, "Default entitlements must be a JSON object", http.StatusBadRequest)
+		return
+	}
+	// Проверяем ключ сразу, чтобы не узнать о проблеме при одобрении
+	if _, err := security.LoadProductKey(keyPath); err != nil {
+		http.Error(w, "Cannot load signing key: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, err = db.DB.Exec(`
+		INSERT INTO products (code, name, signing_key_path, default_tag, default_entitlements)
+		VALUES ($1, $2, $3, $4, $5)`, code, name, keyPath, defaultTag, entitlements)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error creating product", "product", code, "error", err)
+		http.Error(w, "Cannot create product", http.StatusConflict)
+		return
+	}
+	http.Redirect(w, r, "/admin/products", http.StatusSeeOther)
+}
+
+// AddProductVersionHandler добавляет версию продукта
+func AddProductVersionHandler(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+	code := r.FormValue("code")
+	version := strings.TrimSpace(r.FormValue("version"))
+	if version == "" || len(version) > 64 {
+		http.Error(w, "Invalid version", http.StatusBadRequest)
+		return
+	}
+	if _, err := GetProduct(r.Context(), code); err != nil {
+		http.Error(w, "Product not found", http.StatusNotFound)
+		return
+	}
+	_, err := db.DB.Exec(`
+		INSERT INTO product_versions (product_code, version) VALUES ($1, $2)
+		ON CONFLICT DO NOTHING`, code, version)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error adding product version", "product", code, "version", version, "error", err)
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, "/admin/products", http.StatusSeeOther)
+}
@@ -0,0 +1,497 @@
+package licensing
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"example.com/licence-approval/server/pkg/clientcert"
+	"example.com/licence-approval/server/pkg/db"
+	"example.com/licence-approval/server/pkg/metrics"
+	"example.com/licence-approval/server/pkg/orgs"
+	"example.com/licence-approval/server/pkg/security"
+	"example.com/licence-approval/server/pkg/tracing"
+)
+
+// LicenseRequest — заявка на лицензию для конкретного продукта
+type LicenseRequest struct {
+	ID             int
+	LicenseKey     string
+	ProductCode    string
+	ProductVersion string
+	Status         string
+	OrganizationID sql.NullInt64
+	Fingerprint    string
+	RemoteIP       string
+	Hostname       string
+	RequesterEmail string
+	PolicyDecision string
+	IsTrial        bool
+	// Клиент с пробной лицензией запросил полную
+	ConversionRequested bool
+	Tag                 sql.NullInt64
+	LicenseData         sql.NullString
+	Signature           sql.NullString
+	CreatedAt           time.Time
+	DecidedAt           sql.NullTime
+	ExpiresAt           sql.NullTime
+	OriginRequestID     sql.NullInt64
+	// Заявка пришла файлом с машины без доступа к серверу
+	OfflineActivation bool
+	// Открытый ключ установки (Ed25519, base64), которым подписан файл заявки
+	InstallationKey string
+	// Организация подтверждена кодом приглашения или администратором
+	OrganizationVerified bool
+	// Запрос на клиентский сертификат (CSR) и выпущенный по нему сертификат
+	ClientCSR        string
+	ClientCert       string
+	ClientCertSerial string
+	// Сведения от клиента для администратора: кто, зачем и с какой машины
+	RequesterName string
+	Justification string
+	OS            string
+	Arch          string
+	AppVersion    string
+	Metadata      string // JSON-объект произвольных пар ключ/значение
+}
+
+// Origin — первая заявка в цепочке переносов лицензии
+func (lr *LicenseRequest) Origin() int {
+	if lr.OriginRequestID.Valid {
+		return int(lr.OriginRequestID.Int64)
+	}
+	return lr.ID
+}
+
+// Expired — истёк ли срок действия (бывает только у пробных лицензий)
+func (lr *LicenseRequest) Expired(now time.Time) bool {
+	return lr.ExpiresAt.Valid && now.After(lr.ExpiresAt.Time)
+}
+
+// License — содержимое подписываемой лицензии
+type License struct {
+	LicenseKey     string                 `json:"license_key"`
+	Product        string                 `json:"product"`
+	ProductVersion string                 `json:"product_version,omitempty"`
+	Fingerprint    string                 `json:"fingerprint,omitempty"`
+	Tag            int                    `json:"tag"`
+	Entitlements   map[string]interface{} `json:"entitlements"`
+	IssuedAt       time.Time              `json:"issued_at"`
+	Trial          bool                   `json:"trial,omitempty"`
+	ExpiresAt      *time.Time             `json:"expires_at,omitempty"`
+	// Время проверки: только в ответе check-license, по нему клиент отсчитывает grace period
+	CheckedAt *time.Time `json:"checked_at,omitempty"`
+}
+
+var ErrRequestNotFound = errors.New("license request not found")
+
+const requestColumns = `id, license_key, product_code, product_version, status, organization_id,
+	fingerprint, remote_ip, hostname, requester_email, policy_decision,
+	is_trial, conversion_requested, tag, license_data, signature,
+	created_at, decided_at, expires_at, origin_request_id, offline_activation,
+	client_csr, client_cert, client_cert_serial,
+	requester_name, justification, os, arch, app_version, metadata, installation_key,
+	organization_verified`
+
+func scanRequest(row interface{ Scan(...interface{}) error }) (*LicenseRequest, error) {
+	var lr LicenseRequest
+	err := row.Scan(&lr.ID, &lr.LicenseKey, &lr.ProductCode, &lr.ProductVersion, &lr.Status,
+		&lr.OrganizationID, &lr.Fingerprint,
+		&lr.RemoteIP, &lr.Hostname, &lr.RequesterEmail, &lr.PolicyDecision, &lr.IsTrial, &lr.ConversionRequested, &lr.Tag,
+		&lr.LicenseData, &lr.Signature, &lr.CreatedAt, &lr.DecidedAt, &lr.ExpiresAt,
+		&lr.OriginRequestID, &lr.OfflineActivation, &lr.ClientCSR, &lr.ClientCert, &lr.ClientCertSerial,
+		&lr.RequesterName, &lr.Justification, &lr.OS, &lr.Arch, &lr.AppVersion, &lr.Metadata,
+		&lr.InstallationKey, &lr.OrganizationVerified)
+	if err == sql.ErrNoRows {
+		return nil, ErrRequestNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &lr, nil
+}
+
+// GetRequest возвращает заявку по ID
+func GetRequest(ctx context.Context, id int) (lr *LicenseRequest, err error) {
+	ctx, span := tracing.StartDB(ctx, "GetRequest")
+	defer func() { tracing.End(span, err, ErrRequestNotFound) }()
+
+	return scanRequest(db.DB.QueryRowContext(ctx,
+		`SELECT `+requestColumns+` FROM license_requests WHERE id = $1`, id))
+}
+
+// FindLatestRequest возвращает последнюю заявку ключа для продукта
+func FindLatestRequest(ctx context.Context, licenseKey, productCode string) (lr *LicenseRequest, err error) {
+	ctx, span := tracing.StartDB(ctx, "FindLatestRequest")
+	defer func() { tracing.End(span, err, ErrRequestNotFound) }()
+
+	return scanRequest(db.DB.QueryRowContext(ctx, `
+		SELECT `+requestColumns+` FROM license_requests
+		WHERE license_key = $1 AND product_code = $2
+		ORDER BY id DESC LIMIT 1`, licenseKey, productCode))
+}
+
+// ListRequests возвращает заявки продукта (или все, если productCode пуст)
+func ListRequests(productCode string) ([]LicenseRequest, error) {
+	rows, err := db.DB.Query(`
+		SELECT `+requestColumns+` FROM license_requests
+		WHERE $1 = '' OR product_code = $1
+		ORDER BY created_at DESC`, productCode)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []LicenseRequest
+	for rows.Next() {
+		lr, err := scanRequest(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *lr)
+	}
+	return list, rows.Err()
+}
+
+// ListRequestsSince возвращает заявки, созданные после since, от новых к старым
+func ListRequestsSince(since time.Time, limit int) ([]LicenseRequest, error) {
+	rows, err := db.DB.Query(`
+		SELECT `+requestColumns+` FROM license_requests
+		WHERE created_at >= $1
+		ORDER BY created_at DESC LIMIT $2`, since, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []LicenseRequest
+	for rows.Next() {
+		lr, err := scanRequest(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *lr)
+	}
+	return list, rows.Err()
+}
+
+// ListRelatedRequests возвращает другие заявки того же ключа и продукта, а также
+// заявки из цепочки переносов той же лицензии
+func ListRelatedRequests(lr *LicenseRequest) ([]LicenseRequest, error) {
+	rows, err := db.DB.Query(`
+		SELECT `+requestColumns+` FROM license_requests
+		WHERE id <> $1 AND ((license_key = $2 AND product_code = $3)
+			OR id = $4 OR origin_request_id = $4)
+		ORDER BY id`, lr.ID, lr.LicenseKey, lr.ProductCode, lr.Origin())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []LicenseRequest
+	for rows.Next() {
+		r, err := scanRequest(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *r)
+	}
+	return list, rows.Err()
+}
+
+// PendingCounts возвращает число заявок в ожидании по каждому продукту
+func PendingCounts() (map[string]int, error) {
+	rows, err := db.DB.Query(`
+		SELECT product_code, COUNT(*) FROM license_requests
+		WHERE status = $1 OR conversion_requested GROUP BY product_code`, StatusPending)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var code string
+		var n int
+		if err := rows.Scan(&code, &n); err != nil {
+			return nil, err
+		}
+		counts[code] = n
+	}
+	return counts, rows.Err()
+}
+
+// NewRequest — данные новой заявки от клиента
+type NewRequest struct {
+	LicenseKey     string
+	ProductCode    string
+	ProductVersion string
+	OrganizationID int // 0 — без организации
+	Fingerprint    string
+	RemoteIP       string
+	Hostname       string
+	RequesterEmail string
+	Offline        bool // офлайн-активация по файлу заявки
+	// Ключ установки, подписавшей файл офлайн-активации
+	InstallationKey string
+	ClientCSR       string
+	RequesterName   string
+	Justification   string
+	OS              string
+	Arch            string
+	AppVersion      string
+	Metadata        map[string]string
+	// Организация найдена по коду приглашения, а не по заявленному клиентом claim
+	OrganizationVerified bool
+}
+
+// CreateRequest заводит новую заявку в статусе pending
+func CreateRequest(ctx context.Context, nr NewRequest) (_ int, err error) {
+	ctx, span := tracing.StartDB(ctx, "CreateRequest")
+	defer func() { tracing.End(span, err) }()
+
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	id, err := insertRequest(tx, nr)
+	if err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
+}
+
+func insertRequest(q interface {
+	execer
+	QueryRow(string, ...interface{}) *sql.Row
+}, nr NewRequest) (int, error) {
+	var id int
+	err := q.QueryRow(`
+		INSERT INTO license_requests
+			(license_key, product_code, product_version, status, organization_id, fingerprint,
+			 remote_ip, hostname, requester_email, offline_activation, client_csr,
+			 requester_name, justification, os, arch, app_version, metadata, installation_key,
+			 organization_verified, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, NOW())
+		RETURNING id`,
+		nr.LicenseKey, nr.ProductCode, nr.ProductVersion, StatusPending,
+		nullInt(nr.OrganizationID), nr.Fingerprint, nr.RemoteIP, nr.Hostname, nr.RequesterEmail,
+		nr.Offline, nr.ClientCSR, nr.RequesterName, nr.Justification, nr.OS, nr.Arch, nr.AppVersion,
+		encodeMetadata(nr.Metadata), nr.InstallationKey, nr.OrganizationVerified).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	detail := ""
+	if nr.Offline {
+		detail = "offline activation"
+	}
+	return id, recordEvent(q, id, EventCreated, "", StatusPending, ActorClient, detail)
+}
+
+// RequestConversion помечает пробную лицензию как ожидающую перевода в полную
+func RequestConversion(ctx context.Context, id int) (err error) {
+	ctx, span := tracing.StartDB(ctx, "RequestConversion")
+	defer func() { tracing.End(span, err) }()
+
+	res, err := db.DB.ExecContext(ctx, `
+		UPDATE license_requests SET conversion_requested = TRUE
+		WHERE id = $1 AND is_trial AND NOT conversion_requested`, id)
+	if err != nil {
+		return err
+	}
+	// Повторные запросы клиента историю не засоряют
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil
+	}
+	return recordEvent(db.DB, id, EventConversionRequested, StatusApproved, StatusApproved, ActorClient, "")
+}
+
+// AssignOrganization привязывает заявку к организации (0 — отвязать)
+func AssignOrganization(id, orgID int, actor string) error {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var (
+		status, productCode string
+		currentOrg          sql.NullInt64
+		isTrial             bool
+	)
+	err = tx.QueryRow(`
+		SELECT status, product_code, organization_id, is_trial
+		FROM license_requests WHERE id = $1 FOR UPDATE`, id).
+		Scan(&status, &productCode, &currentOrg, &isTrial)
+	if err == sql.ErrNoRows {
+		return ErrRequestNotFound
+	}
+	if err != nil {
+		return err
+	}
+	// Одобренная лицензия занимает квоту новой организации так же, как при одобрении
+	moving := !currentOrg.Valid || int(currentOrg.Int64) != orgID
+	if orgID != 0 && moving && status == StatusApproved && !isTrial {
+		if err := orgs.CheckQuota(tx, orgID, productCode); err != nil {
+			return err
+		}
+	}
+	// Организацию выбрал администратор — она считается подтверждённой
+	if _, err := tx.Exec(`
+		UPDATE license_requests SET organization_id = $1, organization_verified = $2 WHERE id = $3`,
+		nullInt(orgID), orgID != 0, id); err != nil {
+		return err
+	}
+	detail := "organization removed"
+	if orgID != 0 {
+		detail = fmt.Sprintf("organization #%d", orgID)
+	}
+	if err := recordEvent(tx, id, EventOrganizationAssigned, status, status, actor, detail); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Approve подписывает полную лицензию ключом продукта и переводит заявку в approved.
+// Для заявок организации проверяется купленная квота. Одобрение пробной
+// лицензии переводит её в полную. actor — администратор или ActorPolicy.
+func Approve(ctx context.Context, id, tag int, actor string) (err error) {
+	ctx, span := tracing.StartDB(ctx, "Approve")
+	defer func() { tracing.End(span, err) }()
+
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	lr, err := approveTx(ctx, tx, id, tag, nil, actor)
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	// Перевод пробной лицензии в полную — не решение по новой заявке
+	if !lr.IsTrial {
+		decidedBy := "admin"
+		if actor == ActorPolicy {
+			decidedBy = ActorPolicy
+		}
+		metrics.ObserveApproval(decidedBy, time.Since(lr.CreatedAt))
+	}
+	return nil
+}
+
+// approveTx подписывает лицензию в рамках транзакции. expiresAt != nil — пробная лицензия.
+// Возвращает заявку в том виде, в каком она была до одобрения (кроме Status).
+func approveTx(ctx context.Context, tx *sql.Tx, id, tag int, expiresAt *time.Time, actor string) (*LicenseRequest, error) {
+	lr, err := scanRequest(tx.QueryRowContext(ctx,
+		`SELECT `+requestColumns+` FROM license_requests WHERE id = $1 FOR UPDATE`, id))
+	if err != nil {
+		return nil, err
+	}
+	// Одобрить можно ожидающую или отклонённую заявку, а также перевести пробную в полную
+	switch {
+	case lr.Status == StatusPending, lr.Status == StatusRejected:
+	case lr.Status == StatusApproved && lr.IsTrial:
+	default:
+		return nil, fmt.Errorf("request %d cannot be approved in status %s", id, lr.Status)
+	}
+	trial := expiresAt != nil
+	if lr.OrganizationID.Valid && !trial {
+		if err := orgs.CheckQuota(tx, int(lr.OrganizationID.Int64), lr.ProductCode); err != nil {
+			return nil, err
+		}
+	}
+	product, err := GetProduct(ctx, lr.ProductCode)
+	if err != nil {
+		return nil, fmt.Errorf("product %s: %w", lr.ProductCode, err)
+	}
+
+	payload, err := json.Marshal(License{
+		LicenseKey:     lr.LicenseKey,
+		Product:        product.Code,
+		ProductVersion: lr.ProductVersion,
+		Fingerprint:    lr.Fingerprint,
+		Tag:            tag,
+		Entitlements:   product.Entitlements(),
+		IssuedAt:       time.Now().UTC(),
+		Trial:          trial,
+		ExpiresAt:      expiresAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+	signature, err := security.SignLicense(ctx, product.SigningKeyPath, payload)
+	if err != nil {
+		return nil, fmt.Errorf("sign license: %w", err)
+	}
+
+	var expires sql.NullTime
+	if trial {
+		expires = sql.NullTime{Time: *expiresAt, Valid: true}
+	}
+	_, err = tx.Exec(`
+		UPDATE license_requests
+		SET status = $1, tag = $2, license_data = $3, signature = $4, decided_at = NOW(),
+			is_trial = $5, expires_at = $6, conversion_requested = FALSE
+		WHERE id = $7`, StatusApproved, tag, string(payload), signature, trial, expires, id)
+	if err != nil {
+		return nil, err
+	}
+	detail := fmt.Sprintf("TAG %d", tag)
+	switch {
+	case trial:
+		detail += ", trial until " + expiresAt.Format("2006-01-02")
+	case lr.IsTrial:
+		detail += ", converted from trial"
+	}
+	if err := recordEvent(tx, id, EventApproved, lr.Status, StatusApproved, actor, detail); err != nil {
+		return nil, err
+	}
+
+	// Клиентский сертификат выпускается вместе с лицензией, если клиент прислал CSR
+	lr.Status = StatusApproved
+	if lr.ClientCSR != "" && clientcert.CanIssue() {
+		if err := issueClientCert(ctx, tx, lr); err != nil {
+			return nil, err
+		}
+	}
+	return lr, nil
+}
+
+// Reject отклоняет заявку, ожидающую решения
+func Reject(ctx context.Context, id int, actor string) (err error) {
+	ctx, span := tracing.StartDB(ctx, "Reject")
+	defer func() { tracing.End(span, err, ErrRequestNotFound) }()
+
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`
+		UPDATE license_requests SET status = $1, decided_at = NOW()
+		WHERE id = $2 AND status = $3`, StatusRejected, id, StatusPending)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrRequestNotFound
+	}
+	if err := recordEvent(tx, id, EventRejected, StatusPending, StatusRejected, actor, ""); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func nullInt(v int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(v), Valid: v != 0}
+}
@@ -0,0 +1,346 @@
+package licensing
+
+import (
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"example.com/licence-approval/server/pkg/db"
+)
+
+// Решения считаются по истории заявок (license_request_events): статус заявки
+// меняется и после решения (деактивация, перенос), а история — нет. Заявки,
+// созданные переносом лицензии, не считаются: их одобряет сам перенос.
+
+// PendingStats — очередь заявок, ожидающих решения, на текущий момент
+type PendingStats struct {
+	Count       int
+	OlderThan1d int
+	OlderThan7d int
+	Oldest      time.Duration
+	MedianAge   time.Duration
+}
+
+// DayDecisions — одобрения и отклонения за день
+type DayDecisions struct {
+	Day      time.Time
+	Approved int
+	Rejected int
+}
+
+// TagDecisions — одобренные и отклонённые заявки по TAG (Tag.Valid == false — без TAG)
+type TagDecisions struct {
+	Tag      sql.NullInt64
+	Approved int
+	Rejected int
+}
+
+// RequesterStats — заявки одного запрашивающего (email, иначе имя, иначе хост)
+type RequesterStats struct {
+	Requester string
+	Total     int
+	Approved  int
+	Rejected  int
+	Pending   int
+}
+
+// Stats — сводка для панели администратора за период [From, To)
+type Stats struct {
+	From, To      time.Time
+	Pending       PendingStats
+	Days          []DayDecisions
+	ByTag         []TagDecisions
+	TopRequesters []RequesterStats
+	Approved      int
+	Rejected      int
+	// Медиана времени от создания заявки до одобрения; Decided — сколько одобрений учтено
+	MedianTimeToApprove time.Duration
+	Decided             int
+}
+
+// ApprovalRate — доля одобренных среди решений за период, в процентах
+func (s *Stats) ApprovalRate() int {
+	if s.Approved+s.Rejected == 0 {
+		return 0
+	}
+	return s.Approved * 100 / (s.Approved + s.Rejected)
+}
+
+// topRequestersLimit — сколько запрашивающих показывать на панели
+const topRequestersLimit = 10
+
+// CollectStats собирает статистику за период [from, to)
+func CollectStats(from, to time.Time) (*Stats, error) {
+	s := &Stats{From: from, To: to}
+	var err error
+	if s.Pending, err = pendingStats(); err != nil {
+		return nil, err
+	}
+	if s.Days, err = decisionsByDay(from, to); err != nil {
+		return nil, err
+	}
+	for _, d := range s.Days {
+		s.Approved += d.Approved
+		s.Rejected += d.Rejected
+	}
+	if s.ByTag, err = decisionsByTag(from, to); err != nil {
+		return nil, err
+	}
+	if s.TopRequesters, err = topRequesters(from, to, topRequestersLimit); err != nil {
+		return nil, err
+	}
+	if s.Decided, s.MedianTimeToApprove, err = medianTimeToApprove(from, to); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func pendingStats() (PendingStats, error) {
+	var p PendingStats
+	var oldest, median float64
+	err := db.DB.QueryRow(`
+		SELECT COUNT(*),
+			COUNT(*) FILTER (WHERE created_at < NOW() - INTERVAL '1 day'),
+			COUNT(*) FILTER (WHERE created_at < NOW() - INTERVAL '7 days'),
+			COALESCE(EXTRACT(EPOCH FROM NOW() - MIN(created_at)), 0),
+			COALESCE(percentile_cont(0.5) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM NOW() - created_at)), 0)
+		FROM license_requests WHERE status = $1`, StatusPending).
+		Scan(&p.Count, &p.OlderThan1d, &p.OlderThan7d, &oldest, &median)
+	p.Oldest, p.MedianAge = seconds(oldest), seconds(median)
+	return p, err
+}
+
+// decisionsByDay возвращает решения по дням периода, включая дни без решений
+// decisionsByDay раскладывает решения по дням в часовом поясе периода (from):
+// date_trunc в запросе считал бы дни в поясе базы, а границы периода — в поясе сервера
+func decisionsByDay(from, to time.Time) ([]DayDecisions, error) {
+	rows, err := db.DB.Query(`
+		SELECT e.created_at, e.action
+		FROM license_request_events e
+		JOIN license_requests r ON r.id = e.request_id
+		WHERE e.created_at >= $1 AND e.created_at < $2
+			AND e.action IN ($3, $4) AND r.origin_request_id IS NULL`,
+		from, to, EventApproved, EventRejected)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []decisionEvent
+	for rows.Next() {
+		var e decisionEvent
+		if err := rows.Scan(&e.At, &e.Action); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return bucketByDay(from, to, events), nil
+}
+
+// decisionEvent — одно решение по заявке из истории
+type decisionEvent struct {
+	At     time.Time
+	Action string
+}
+
+// bucketByDay считает решения по календарным дням периода [from, to) в поясе from;
+// дни без решений тоже попадают в результат
+func bucketByDay(from, to time.Time, events []decisionEvent) []DayDecisions {
+	loc := from.Location()
+	byDay := make(map[string]DayDecisions)
+	for _, e := range events {
+		key := e.At.In(loc).Format("2006-01-02")
+		d := byDay[key]
+		switch e.Action {
+		case EventApproved:
+			d.Approved++
+		case EventRejected:
+			d.Rejected++
+		}
+		byDay[key] = d
+	}
+
+	var days []DayDecisions
+	for day := from; day.Before(to); day = day.AddDate(0, 0, 1) {
+		d := byDay[day.Format("2006-01-02")]
+		d.Day = day
+		days = append(days, d)
+	}
+	return days
+}
+
+func decisionsByTag(from, to time.Time) ([]TagDecisions, error) {
+	rows, err := db.DB.Query(`
+		SELECT r.tag,
+			COUNT(*) FILTER (WHERE e.action = $3),
+			COUNT(*) FILTER (WHERE e.action = $4)
+		FROM license_request_events e
+		JOIN license_requests r ON r.id = e.request_id
+		WHERE e.created_at >= $1 AND e.created_at < $2
+			AND e.action IN ($3, $4) AND r.origin_request_id IS NULL
+		GROUP BY r.tag
+		ORDER BY r.tag NULLS LAST`, from, to, EventApproved, EventRejected)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []TagDecisions
+	for rows.Next() {
+		var t TagDecisions
+		if err := rows.Scan(&t.Tag, &t.Approved, &t.Rejected); err != nil {
+			return nil, err
+		}
+		list = append(list, t)
+	}
+	return list, rows.Err()
+}
+
+// topRequesters — запрашивающие с наибольшим числом заявок, созданных за период
+func topRequesters(from, to time.Time, limit int) ([]RequesterStats, error) {
+	rows, err := db.DB.Query(`
+		SELECT COALESCE(NULLIF(requester_email, ''), NULLIF(requester_name, ''), hostname) AS requester,
+			COUNT(*),
+			COUNT(*) FILTER (WHERE status IN ($4, $5, $6)),
+			COUNT(*) FILTER (WHERE status = $7),
+			COUNT(*) FILTER (WHERE status = $8)
+		FROM license_requests
+		WHERE created_at >= $1 AND created_at < $2 AND origin_request_id IS NULL
+		GROUP BY requester
+		ORDER BY COUNT(*) DESC, requester
+		LIMIT $3`, from, to, limit,
+		StatusApproved, StatusReleased, StatusTransferred, StatusRejected, StatusPending)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []RequesterStats
+	for rows.Next() {
+		var s RequesterStats
+		if err := rows.Scan(&s.Requester, &s.Total, &s.Approved, &s.Rejected, &s.Pending); err != nil {
+			return nil, err
+		}
+		list = append(list, s)
+	}
+	return list, rows.Err()
+}
+
+// medianTimeToApprove — медиана времени до первого одобрения полных лицензий,
+// одобренных за период (пробные выдаются сразу и не учитываются)
+func medianTimeToApprove(from, to time.Time) (int, time.Duration, error) {
+	var n int
+	var median float64
+	err := db.DB.QueryRow(`
+		SELECT COUNT(*),
+			COALESCE(percentile_cont(0.5) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM e.created_at - r.created_at)), 0)
+		FROM license_request_events e
+		JOIN license_requests r ON r.id = e.request_id
+		WHERE e.created_at >= $1 AND e.created_at < $2
+			AND e.action = $3 AND e.from_status <> $4
+			AND NOT r.is_trial AND r.origin_request_id IS NULL`,
+		from, to, EventApproved, StatusApproved).Scan(&n, &median)
+	return n, seconds(median), err
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// Периоды панели: кнопки быстрого выбора (в днях), период по умолчанию и наибольший
+var dashboardPresets = []int{7, 30, 90, 365}
+
+const (
+	defaultStatsDays = 30
+	maxStatsDays     = 366
+)
+
+// dashboardPage — данные для admin_dashboard.html
+type dashboardPage struct {
+	*Stats
+	Presets []int
+	Preset  int // выбранная кнопка периода в днях; 0 — период задан датами
+	// Значения полей формы; To — последний день периода включительно
+	FromValue string
+	ToValue   string
+	maxPerDay int
+}
+
+// Percent — доля n от наибольшего числа решений за день, для высоты столбца диаграммы
+func (p dashboardPage) Percent(n int) int {
+	if p.maxPerDay == 0 {
+		return 0
+	}
+	return n * 100 / p.maxPerDay
+}
+
+// DashboardHandler — GET /admin/dashboard?days=30 или ?from=2024-01-01&to=2024-01-31
+func DashboardHandler(w http.ResponseWriter, r *http.Request) {
+	from, to, days, err := statsRange(r, time.Now())
+	if err != nil {
+		http.Error(w, "Invalid period: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	stats, err := CollectStats(from, to)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error collecting statistics", "error", err)
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+
+	page := dashboardPage{
+		Stats:     stats,
+		Presets:   dashboardPresets,
+		Preset:    days,
+		FromValue: from.Format("2006-01-02"),
+		ToValue:   to.AddDate(0, 0, -1).Format("2006-01-02"),
+	}
+	for _, d := range stats.Days {
+		page.maxPerDay = max(page.maxPerDay, d.Approved+d.Rejected)
+	}
+	if err := tmpl.Render(w, r, "admin_dashboard.html", page); err != nil {
+		slog.ErrorContext(r.Context(), "Error rendering dashboard", "error", err)
+	}
+}
+
+// statsRange разбирает период панели: даты from и to (включительно) или число
+// последних дней days. Возвращает границы [from, to) и выбранное число дней
+// (0, если период задан датами).
+func statsRange(r *http.Request, now time.Time) (from, to time.Time, days int, err error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	q := r.URL.Query()
+
+	if q.Get("from") == "" && q.Get("to") == "" {
+		days = defaultStatsDays
+		if v := q.Get("days"); v != "" {
+			if days, err = strconv.Atoi(v); err != nil || days < 1 || days > maxStatsDays {
+				return from, to, 0, errors.New("invalid number of days")
+			}
+		}
+		return today.AddDate(0, 0, 1-days), today.AddDate(0, 0, 1), days, nil
+	}
+
+	if from, err = time.ParseInLocation("2006-01-02", q.Get("from"), now.Location()); err != nil {
+		return from, to, 0, errors.New("invalid start date")
+	}
+	last := today
+	if v := q.Get("to"); v != "" {
+		if last, err = time.ParseInLocation("2006-01-02", v, now.Location()); err != nil {
+			return from, to, 0, errors.New("invalid end date")
+		}
+	}
+	to = last.AddDate(0, 0, 1)
+	switch {
+	case !from.Before(to):
+		return from, to, 0, errors.New("start date is after end date")
+	case to.After(from.AddDate(0, 0, maxStatsDays)):
+		return from, to, 0, errors.New("date range is too long")
+	}
+	return from, to, 0, nil
+}
@@ -0,0 +1,85 @@
+package licensing
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestStatsRange(t *testing.T) {
+	msk := time.FixedZone("MSK", 3*60*60)
+	now := time.Date(2026, 10, 19, 15, 30, 0, 0, msk)
+	date := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, msk) }
+
+	tests := []struct {
+		name     string
+		query    string
+		wantFrom time.Time
+		wantTo   time.Time
+		wantDays int
+		wantErr  bool
+	}{
+		{name: "default period", query: "", wantFrom: date(2026, 9, 20), wantTo: date(2026, 10, 20), wantDays: defaultStatsDays},
+		{name: "today only", query: "days=1", wantFrom: date(2026, 10, 19), wantTo: date(2026, 10, 20), wantDays: 1},
+		{name: "last week", query: "days=7", wantFrom: date(2026, 10, 13), wantTo: date(2026, 10, 20), wantDays: 7},
+		{name: "max days", query: "days=366", wantFrom: date(2025, 10, 19), wantTo: date(2026, 10, 20), wantDays: maxStatsDays},
+		{name: "zero days", query: "days=0", wantErr: true},
+		{name: "too many days", query: "days=367", wantErr: true},
+		{name: "days not a number", query: "days=week", wantErr: true},
+		// Конечная дата включается в период
+		{name: "date range", query: "from=2026-10-01&to=2026-10-05", wantFrom: date(2026, 10, 1), wantTo: date(2026, 10, 6)},
+		{name: "single day", query: "from=2026-10-05&to=2026-10-05", wantFrom: date(2026, 10, 5), wantTo: date(2026, 10, 6)},
+		{name: "open end is today", query: "from=2026-10-01", wantFrom: date(2026, 10, 1), wantTo: date(2026, 10, 20)},
+		{name: "dates take precedence over days", query: "from=2026-10-01&to=2026-10-02&days=7", wantFrom: date(2026, 10, 1), wantTo: date(2026, 10, 3)},
+		{name: "end without start", query: "to=2026-10-05", wantErr: true},
+		{name: "invalid start", query: "from=01.10.2026", wantErr: true},
+		{name: "invalid end", query: "from=2026-10-01&to=tomorrow", wantErr: true},
+		{name: "start after end", query: "from=2026-10-06&to=2026-10-05", wantErr: true},
+		{name: "longest range", query: "from=2025-10-19&to=2026-10-19", wantFrom: date(2025, 10, 19), wantTo: date(2026, 10, 20)},
+		{name: "range too long", query: "from=2025-10-18&to=2026-10-19", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/admin/dashboard?"+tt.query, nil)
+			from, to, days, err := statsRange(r, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("statsRange(%q) error = %v, wantErr %v", tt.query, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !from.Equal(tt.wantFrom) || !to.Equal(tt.wantTo) || days != tt.wantDays {
+				t.Errorf("statsRange(%q) = [%s, %s), %d days; want [%s, %s), %d days",
+					tt.query, from, to, days, tt.wantFrom, tt.wantTo, tt.wantDays)
+			}
+		})
+	}
+}
+
+func TestBucketByDay(t *testing.T) {
+	msk := time.FixedZone("MSK", 3*60*60)
+	from := time.Date(2026, 10, 18, 0, 0, 0, 0, msk)
+	to := from.AddDate(0, 0, 2)
+
+	days := bucketByDay(from, to, []decisionEvent{
+		// 22:30 UTC 17.10 — уже 18.10 по Москве
+		{At: time.Date(2026, 10, 17, 22, 30, 0, 0, time.UTC), Action: EventApproved},
+		{At: time.Date(2026, 10, 18, 20, 59, 0, 0, time.UTC), Action: EventRejected},
+		// 21:00 UTC 18.10 — полночь 19.10 по Москве
+		{At: time.Date(2026, 10, 18, 21, 0, 0, 0, time.UTC), Action: EventApproved},
+		{At: time.Date(2026, 10, 19, 12, 0, 0, 0, msk), Action: EventApproved},
+	})
+
+	want := []DayDecisions{
+		{Day: from, Approved: 1, Rejected: 1},
+		{Day: from.AddDate(0, 0, 1), Approved: 2},
+	}
+	if len(days) != len(want) {
+		t.Fatalf("bucketByDay() returned %d days, want %d", len(days), len(want))
+	}
+	for i := range want {
+		if !days[i].Day.Equal(want[i].Day) || days[i].Approved != want[i].Approved || days[i].Rejected != want[i].Rejected {
+			t.Errorf("day %d = %+v, want %+v", i, days[i], want[i])
+		}
+	}
+}
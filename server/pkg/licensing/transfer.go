@@ -0,0 +1,196 @@
+package licensing
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"example.com/licence-approval/server/pkg/db"
+	"example.com/licence-approval/server/pkg/tracing"
+)
+
+// Ограничение на число переносов одной лицензии за период
+var (
+	transferLimit  = 2
+	transferWindow = 30 * 24 * time.Hour
+)
+
+var (
+	ErrNotActive             = errors.New("license is not active")
+	ErrFingerprintMismatch   = errors.New("license is activated on another machine")
+	ErrTransferLimitExceeded = errors.New("transfer limit for this license exceeded")
+	ErrIdentityRequired      = errors.New("client certificate is required to release an activation without fingerprint")
+	ErrKeyInUse              = errors.New("new license key already has a pending or approved request")
+)
+
+// ConfigureTransfers задаёт лимит переносов за период; limit 0 запрещает переносы
+func ConfigureTransfers(limit int, window time.Duration) {
+	if limit >= 0 {
+		transferLimit = limit
+	}
+	if window > 0 {
+		transferWindow = window
+	}
+}
+
+// Deactivate освобождает активацию по запросу клиента. Если у заявки сохранён
+// отпечаток машины, освободить её может только та же машина; без отпечатка —
+// только клиент с проверенным сертификатом на этот ключ (identified).
+func Deactivate(ctx context.Context, licenseKey, productCode, fingerprint string, identified bool) (_ int, err error) {
+	ctx, span := tracing.StartDB(ctx, "Deactivate")
+	defer func() { tracing.End(span, err, ErrNotActive, ErrFingerprintMismatch, ErrIdentityRequired) }()
+
+	lr, err := FindLatestRequest(ctx, licenseKey, productCode)
+	if err == ErrRequestNotFound {
+		return 0, ErrNotActive
+	}
+	if err != nil {
+		return 0, err
+	}
+	if lr.Status != StatusApproved {
+		return 0, ErrNotActive
+	}
+	if lr.Fingerprint != "" && lr.Fingerprint != fingerprint {
+		return 0, ErrFingerprintMismatch
+	}
+	if lr.Fingerprint == "" && !identified {
+		return 0, ErrIdentityRequired
+	}
+	return lr.ID, release(ctx, lr.ID, ActorClient)
+}
+
+// Release освобождает активацию по решению администратора
+func Release(ctx context.Context, id int, actor string) (err error) {
+	ctx, span := tracing.StartDB(ctx, "Release")
+	defer func() { tracing.End(span, err, ErrNotActive) }()
+
+	return release(ctx, id, actor)
+}
+
+func release(ctx context.Context, id int, actor string) error {
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `
+		UPDATE license_requests SET status = $1, decided_at = NOW()
+		WHERE id = $2 AND status = $3`, StatusReleased, id, StatusApproved)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotActive
+	}
+	if err := recordEvent(tx, id, EventReleased, StatusApproved, StatusReleased, actor, ""); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Transfer переносит одобренную лицензию на новый ключ (и, при необходимости, отпечаток):
+// старая заявка получает статус transferred, новая — approved с тем же TAG и организацией.
+func Transfer(ctx context.Context, id int, newKey, newFingerprint, actor string) (_ int, err error) {
+	ctx, span := tracing.StartDB(ctx, "Transfer")
+	defer func() { tracing.End(span, err, ErrNotActive, ErrTransferLimitExceeded, ErrKeyInUse) }()
+
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	lr, err := scanRequest(tx.QueryRowContext(ctx,
+		`SELECT `+requestColumns+` FROM license_requests WHERE id = $1 FOR UPDATE`, id))
+	if err != nil {
+		return 0, err
+	}
+	if lr.Status != StatusApproved || lr.Expired(time.Now()) {
+		return 0, ErrNotActive
+	}
+	if newKey == lr.LicenseKey && newFingerprint == lr.Fingerprint {
+		return 0, errors.New("new key and fingerprint are the same as the current ones")
+	}
+
+	// У нового ключа не должно быть своей живой заявки на этот продукт
+	// (смена только отпечатка переносит лицензию на тот же ключ)
+	if newKey != lr.LicenseKey {
+		latest, err := scanRequest(tx.QueryRowContext(ctx, `
+			SELECT `+requestColumns+` FROM license_requests
+			WHERE license_key = $1 AND product_code = $2
+			ORDER BY id DESC LIMIT 1`, newKey, lr.ProductCode))
+		if err != nil && err != ErrRequestNotFound {
+			return 0, err
+		}
+		if latest != nil && (latest.Status == StatusPending || latest.Status == StatusApproved) {
+			return 0, ErrKeyInUse
+		}
+	}
+
+	origin := lr.Origin()
+	recent, err := transferCount(tx, origin)
+	if err != nil {
+		return 0, err
+	}
+	if recent >= transferLimit {
+		return 0, ErrTransferLimitExceeded
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE license_requests SET status = $1, decided_at = NOW() WHERE id = $2`,
+		StatusTransferred, id); err != nil {
+		return 0, err
+	}
+
+	var newID int
+	err = tx.QueryRow(`
+		INSERT INTO license_requests
+			(license_key, product_code, product_version, status, organization_id, fingerprint,
+			 remote_ip, hostname, requester_email, origin_request_id,
+			 requester_name, justification, app_version, metadata, organization_verified, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, '', '', $7, $8, $9, $10, $11, $12, $13, NOW()) RETURNING id`,
+		newKey, lr.ProductCode, lr.ProductVersion, StatusPending, lr.OrganizationID,
+		newFingerprint, lr.RequesterEmail, origin,
+		lr.RequesterName, lr.Justification, lr.AppVersion, lr.Metadata, lr.OrganizationVerified).Scan(&newID)
+	if err != nil {
+		return 0, fmt.Errorf("create transferred request: %w", err)
+	}
+	if err := recordEvent(tx, id, EventTransferred, StatusApproved, StatusTransferred, actor,
+		fmt.Sprintf("to request #%d", newID)); err != nil {
+		return 0, err
+	}
+	if err := recordEvent(tx, newID, EventCreated, "", StatusPending, actor,
+		fmt.Sprintf("transfer from request #%d", id)); err != nil {
+		return 0, err
+	}
+
+	var expiresAt *time.Time
+	if lr.IsTrial && lr.ExpiresAt.Valid {
+		expiresAt = &lr.ExpiresAt.Time
+	}
+	if _, err := approveTx(ctx, tx, newID, int(lr.Tag.Int64), expiresAt, actor); err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.Exec(`
+		INSERT INTO license_transfers (origin_request_id, from_request_id, to_request_id, actor)
+		VALUES ($1, $2, $3, $4)`, origin, id, newID, actor); err != nil {
+		return 0, err
+	}
+	return newID, tx.Commit()
+}
+
+// transferCount — сколько раз лицензия переносилась за текущий период
+func transferCount(q interface {
+	QueryRow(string, ...interface{}) *sql.Row
+}, origin int) (int, error) {
+	var n int
+	err := q.QueryRow(`
+		SELECT COUNT(*) FROM license_transfers
+		WHERE origin_request_id = $1 AND created_at > $2`,
+		origin, time.Now().Add(-transferWindow)).Scan(&n)
+	return n, err
+}
@@ -0,0 +1,66 @@
+package licensing
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"example.com/licence-approval/server/pkg/db"
+	"example.com/licence-approval/server/pkg/tracing"
+)
+
+// Параметры пробных лицензий
+var (
+	trialDuration = 14 * 24 * time.Hour
+	trialTag      = 1
+)
+
+var ErrTrialUsed = errors.New("trial already used on this machine")
+
+// ConfigureTrials задаёт длительность пробной лицензии и её (урезанный) TAG
+func ConfigureTrials(duration time.Duration, tag int) {
+	if duration > 0 {
+		trialDuration = duration
+	}
+	if tag >= 1 && tag <= 1000 {
+		trialTag = tag
+	}
+}
+
+// CreateTrial заводит заявку и сразу одобряет пробную лицензию, если у машины
+// ещё не было пробной лицензии этого продукта.
+func CreateTrial(ctx context.Context, nr NewRequest) (_ int, err error) {
+	if nr.Fingerprint == "" {
+		return 0, errors.New("machine fingerprint is required for a trial")
+	}
+	ctx, span := tracing.StartDB(ctx, "CreateTrial")
+	defer func() { tracing.End(span, err, ErrTrialUsed) }()
+
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	id, err := insertRequest(tx, nr)
+	if err != nil {
+		return 0, err
+	}
+
+	// Отпечаток машины «занимается» атомарно: повторная пробная лицензия невозможна
+	res, err := tx.Exec(`
+		INSERT INTO trial_fingerprints (fingerprint, product_code, request_id)
+		VALUES ($1, $2, $3) ON CONFLICT DO NOTHING`, nr.Fingerprint, nr.ProductCode, id)
+	if err != nil {
+		return 0, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return 0, ErrTrialUsed
+	}
+
+	expiresAt := time.Now().UTC().Add(trialDuration).Truncate(time.Second)
+	if _, err := approveTx(ctx, tx, id, trialTag, &expiresAt, ActorSystem); err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
+}
@@ -0,0 +1,73 @@
+// Package logging — структурированные логи сервера (log/slog): формат JSON или
+// текст, уровень, идентификатор запроса и trace id в каждой записи, журнал
+// запросов и маскирование секретов (ключей лицензий, кодов, токенов).
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Форматы логов (LOG_FORMAT)
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Configure делает логгером по умолчанию slog-обработчик формата format
+// с уровнем level (debug, info, warn, error). Стандартный log тоже пишет
+// через него — на уровне info.
+func Configure(format, level string) error {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return fmt.Errorf("invalid log level %q", level)
+	}
+	opts := &slog.HandlerOptions{Level: lvl, ReplaceAttr: redactAttr}
+
+	var h slog.Handler
+	switch strings.ToLower(format) {
+	case FormatText, "":
+		h = slog.NewTextHandler(os.Stderr, opts)
+	case FormatJSON:
+		h = slog.NewJSONHandler(os.Stderr, opts)
+	default:
+		return fmt.Errorf("invalid log format %q (want %s or %s)", format, FormatText, FormatJSON)
+	}
+	slog.SetDefault(slog.New(contextHandler{h}))
+	return nil
+}
+
+// Fatal пишет ошибку и завершает процесс — замена log.Fatalf при запуске
+func Fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+// contextHandler добавляет к записям, сделанным с контекстом запроса
+// (slog.InfoContext и т. п.), его идентификатор и trace id
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := RequestIDFrom(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r.AddAttrs(slog.String("trace_id", sc.TraceID().String()))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,103 @@
+package logging
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "", want: "[redacted]"},
+		{in: "short", want: "[redacted]"},
+		{in: "0123456789abcde", want: "[redacted]"},
+		{in: "0123456789abcdef", want: "0123…[redacted]"},
+		{in: "LIC-7f3a9c2e-41b8-4d0e-a6f1-93c5e2b7d104", want: "LIC-…[redacted]"},
+	}
+	for _, tt := range tests {
+		if got := Redact(tt.in); got != tt.want {
+			t.Errorf("Redact(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestRedactAttr(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{ReplaceAttr: redactAttr}))
+	logger.Info("test",
+		"license_key", "LIC-7f3a9c2e-41b8-4d0e-a6f1-93c5e2b7d104",
+		"Authorization", "hunter2",
+		"token", "",
+		"request_id", 42,
+		"path", "/api/check-license",
+	)
+	out := buf.String()
+	for _, secret := range []string{"7f3a9c2e", "hunter2"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("log line leaks %q: %s", secret, out)
+		}
+	}
+	for _, want := range []string{`license_key=LIC-…[redacted]`, `Authorization=[redacted]`, `token=""`, `request_id=42`, `path=/api/check-license`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("log line %s does not contain %s", out, want)
+		}
+	}
+}
+
+func TestValidRequestID(t *testing.T) {
+	tests := []struct {
+		id   string
+		want bool
+	}{
+		{id: "", want: false},
+		{id: "3f2a9c1e8b7d4a60", want: true},
+		{id: "req_2026-10-19.01", want: true},
+		{id: strings.Repeat("a", maxRequestIDLen), want: true},
+		{id: strings.Repeat("a", maxRequestIDLen+1), want: false},
+		{id: "abc\nlevel=ERROR msg=forged", want: false},
+		{id: "abc def", want: false},
+		{id: `abc"def`, want: false},
+		{id: "идентификатор", want: false},
+	}
+	for _, tt := range tests {
+		if got := validRequestID(tt.id); got != tt.want {
+			t.Errorf("validRequestID(%q) = %v, want %v", tt.id, got, tt.want)
+		}
+	}
+}
+
+func TestMiddlewareRequestID(t *testing.T) {
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+
+	var seen string
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = RequestIDFrom(r.Context())
+	}))
+
+	// Корректный идентификатор клиента сохраняется
+	r := httptest.NewRequest(http.MethodGet, "/api/check-license", nil)
+	r.Header.Set(RequestIDHeader, "client-req-1")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, r)
+	if seen != "client-req-1" || rec.Header().Get(RequestIDHeader) != "client-req-1" {
+		t.Errorf("request ID = %q, header %q; want client-req-1", seen, rec.Header().Get(RequestIDHeader))
+	}
+
+	// Недопустимый заменяется новым
+	r = httptest.NewRequest(http.MethodGet, "/api/check-license", nil)
+	r.Header.Set(RequestIDHeader, "bad id\n")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, r)
+	if !validRequestID(seen) || seen == "bad id\n" || rec.Header().Get(RequestIDHeader) != seen {
+		t.Errorf("request ID = %q, header %q; want a new valid ID", seen, rec.Header().Get(RequestIDHeader))
+	}
+}
@@ -0,0 +1,41 @@
+package logging
+
+import (
+	"log/slog"
+	"strings"
+)
+
+// secretKeys — атрибуты, значения которых в логах маскируются
+var secretKeys = map[string]bool{
+	"license_key":   true,
+	"new_key":       true,
+	"code":          true,
+	"token":         true,
+	"access_token":  true,
+	"id_token":      true,
+	"refresh_token": true,
+	"client_secret": true,
+	"password":      true,
+	"authorization": true,
+	"cookie":        true,
+}
+
+// visiblePrefix — сколько первых символов длинного секрета остаётся видно,
+// чтобы записи можно было сопоставить с заявкой в админке
+const visiblePrefix = 4
+
+// Redact маскирует секрет: у длинных значений остаётся только начало
+func Redact(s string) string {
+	if len(s) < 4*visiblePrefix {
+		return "[redacted]"
+	}
+	return s[:visiblePrefix] + "…[redacted]"
+}
+
+// redactAttr — ReplaceAttr обработчиков: маскирует атрибуты из secretKeys
+func redactAttr(_ []string, a slog.Attr) slog.Attr {
+	if secretKeys[strings.ToLower(a.Key)] && a.Value.Kind() == slog.KindString && a.Value.String() != "" {
+		return slog.String(a.Key, Redact(a.Value.String()))
+	}
+	return a
+}
@@ -0,0 +1,141 @@
+// Package metrics — метрики Prometheus сервера лицензий: HTTP-запросы по маршрутам,
+// время до одобрения заявок и операции подписи. Отдаются на отдельном адресе.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "license_server"
+
+// Registry — реестр метрик сервера; коллекторы, которым нужна БД, добавляет main
+var Registry = prometheus.NewRegistry()
+
+// Виды операций подписи
+const (
+	SigningLicense           = "license"
+	SigningClientCertificate = "client_certificate"
+)
+
+// unmatchedRoute — метка маршрута для запросов, не попавших ни в один маршрут
+const unmatchedRoute = "unmatched"
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by route template, method and status code.",
+	}, []string{"route", "method", "code"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request handling time by route template and method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method"})
+
+	// От минуты до полутора недель: заявки решаются людьми
+	approvalLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "approval_latency_seconds",
+		Help:      "Time from license request creation to approval.",
+		Buckets:   prometheus.ExponentialBuckets(60, 4, 8),
+	}, []string{"decided_by"})
+
+	signingOperations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "signing_operations_total",
+		Help:      "Signing operations by kind (license, client_certificate) and result.",
+	}, []string{"kind", "result"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests, httpDuration, approvalLatency, signingOperations,
+	)
+}
+
+// Handler отдаёт метрики в формате Prometheus
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// NewServer собирает отдельный HTTP-листенер с /metrics. Запускает и
+// останавливает его вызывающий — вместе с основным сервером.
+func NewServer(addr string) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Handler())
+	return &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+}
+
+// Middleware считает запросы и время их обработки. Маршрут берётся шаблоном
+// gorilla/mux (/admin/license-request, а не конкретный URL), чтобы число рядов
+// не зависело от параметров запросов.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := unmatchedRoute
+		if current := mux.CurrentRoute(r); current != nil {
+			if tmpl, err := current.GetPathTemplate(); err == nil {
+				route = tmpl
+			}
+		}
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		start := time.Now()
+		next.ServeHTTP(rec, r)
+
+		httpDuration.WithLabelValues(route, r.Method).Observe(time.Since(start).Seconds())
+		httpRequests.WithLabelValues(route, r.Method, strconv.Itoa(rec.status)).Inc()
+	})
+}
+
+// ObserveApproval учитывает время от создания заявки до её одобрения;
+// decidedBy — admin или policy
+func ObserveApproval(decidedBy string, d time.Duration) {
+	approvalLatency.WithLabelValues(decidedBy).Observe(d.Seconds())
+}
+
+// CountSigning учитывает операцию подписи вида kind и её результат
+func CountSigning(kind string, err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	signingOperations.WithLabelValues(kind, result).Inc()
+}
+
+// statusRecorder запоминает код ответа обработчика
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	if !r.wroteHeader {
+		r.status, r.wroteHeader = code, true
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	return r.ResponseWriter.Write(b)
+}
+
+// Unwrap даёт http.ResponseController доступ к исходному ResponseWriter
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
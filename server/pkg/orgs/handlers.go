@@ -0,0 +1,121 @@
+package orgs
+
+import (
+	"log/slog"
+	"net/http"
+	"net/mail"
+	"strconv"
+	"strings"
+
+	"example.com/licence-approval/server/pkg/db"
+)
+
+// organizationsPage — данные для admin_organizations.html
+type organizationsPage struct {
+	Organizations []Organization
+	Products      []string
+}
+
+// OrganizationsHandler показывает организации, контакты и использование квот
+func OrganizationsHandler(w http.ResponseWriter, r *http.Request) {
+	list, err := List()
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error listing organizations", "error", err)
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+	products, err := productCodes()
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error listing products", "error", err)
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+	page := organizationsPage{Organizations: list, Products: products}
+	if err := tmpl.Render(w, r, "admin_organizations.html", page); err != nil {
+		slog.ErrorContext(r.Context(), "Error rendering organizations", "error", err)
+	}
+}
+
+// CreateOrganizationHandler заводит организацию и генерирует ей код приглашения
+func CreateOrganizationHandler(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimSpace(r.FormValue("name"))
+	claim := strings.ToLower(strings.TrimSpace(r.FormValue("claim")))
+	if name == "" {
+		http.Error(w, "Organization name is required", http.StatusBadRequest)
+		return
+	}
+	invite, err := newInviteCode()
+	if err != nil {
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+
+	var claimValue interface{}
+	if claim != "" {
+		claimValue = claim
+	}
+	_, err = db.DB.Exec(`INSERT INTO organizations (name, claim, invite_code) VALUES ($1, $2, $3)`,
+		name, claimValue, invite)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error creating organization", "name", name, "error", err)
+		http.Error(w, "Cannot create organization (name or claim already used)", http.StatusConflict)
+		return
+	}
+	http.Redirect(w, r, "/admin/organizations", http.StatusSeeOther)
+}
+
+// AddContactHandler добавляет контактное лицо
+func AddContactHandler(w http.ResponseWriter, r *http.Request) {
+	orgID, err := strconv.Atoi(r.FormValue("organization_id"))
+	if err != nil {
+		http.Error(w, "Invalid organization ID", http.StatusBadRequest)
+		return
+	}
+	name := strings.TrimSpace(r.FormValue("name"))
+	email := strings.TrimSpace(r.FormValue("email"))
+	if name == "" {
+		http.Error(w, "Contact name is required", http.StatusBadRequest)
+		return
+	}
+	if _, err := mail.ParseAddress(email); err != nil {
+		http.Error(w, "Invalid email", http.StatusBadRequest)
+		return
+	}
+
+	_, err = db.DB.Exec(`
+		INSERT INTO organization_contacts (organization_id, name, email, role)
+		VALUES ($1, $2, $3, $4)`, orgID, name, email, strings.TrimSpace(r.FormValue("role")))
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error adding contact to organization", "organization_id", orgID, "error", err)
+		http.Error(w, "Cannot add contact", http.StatusConflict)
+		return
+	}
+	http.Redirect(w, r, "/admin/organizations", http.StatusSeeOther)
+}
+
+// SetQuotaHandler задаёт купленную квоту по продукту
+func SetQuotaHandler(w http.ResponseWriter, r *http.Request) {
+	orgID, err := strconv.Atoi(r.FormValue("organization_id"))
+	if err != nil {
+		http.Error(w, "Invalid organization ID", http.StatusBadRequest)
+		return
+	}
+	quota, err := strconv.Atoi(r.FormValue("quota"))
+	if err != nil || quota < 0 {
+		http.Error(w, "Invalid quota", http.StatusBadRequest)
+		return
+	}
+	product := r.FormValue("product")
+
+	_, err = db.DB.Exec(`
+		INSERT INTO organization_quotas (organization_id, product_code, quota)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (organization_id, product_code) DO UPDATE SET quota = EXCLUDED.quota`,
+		orgID, product, quota)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error setting organization quota", "organization_id", orgID, "error", err)
+		http.Error(w, "Cannot set quota", http.StatusConflict)
+		return
+	}
+	http.Redirect(w, r, "/admin/organizations", http.StatusSeeOther)
+}
@@ -0,0 +1,99 @@
+// Package orgs — организации-заказчики, их контакты и купленные квоты лицензий по продуктам.
+package orgs
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"example.com/licence-approval/server/pkg/db"
+	"example.com/licence-approval/server/pkg/logging"
+	"example.com/licence-approval/server/templates"
+)
+
+// Organization — заказчик (компания или команда)
+type Organization struct {
+	ID         int
+	Name       string
+	Claim      sql.NullString // идентификатор, который клиент может указать сам (например, домен)
+	InviteCode string
+	CreatedAt  time.Time
+	Contacts   []Contact
+	Usage      []QuotaUsage
+}
+
+// Contact — контактное лицо организации
+type Contact struct {
+	ID    int
+	Name  string
+	Email string
+	Role  string
+}
+
+// QuotaUsage — квота организации по продукту и её использование
+type QuotaUsage struct {
+	ProductCode string
+	Quota       int
+	Used        int // одобренные полные лицензии (пробные квоту не расходуют)
+	Pending     int
+}
+
+// Exceeded — исчерпана ли квота
+func (u QuotaUsage) Exceeded() bool {
+	return u.Used >= u.Quota
+}
+
+var ErrQuotaExceeded = errors.New("organization license quota exceeded")
+
+var tmpl *templates.Set
+
+// Init запоминает шаблоны админки, разобранные в main
+func Init(set *templates.Set) {
+	tmpl = set
+}
+
+// Migrate создаёт таблицы организаций и связь заявок с организацией
+func Migrate() {
+	stmts := []string{
+		`CREATE TABLE IF NOT EXISTS organizations (
+			id          SERIAL PRIMARY KEY,
+			name        TEXT NOT NULL UNIQUE,
+			claim       TEXT UNIQUE,
+			invite_code TEXT NOT NULL UNIQUE,
+			created_at  TIMESTAMP NOT NULL DEFAULT NOW()
+		)`,
+		`CREATE TABLE IF NOT EXISTS organization_contacts (
+			id              SERIAL PRIMARY KEY,
+			organization_id INTEGER NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+			name            TEXT NOT NULL,
+			email           TEXT NOT NULL,
+			role            TEXT NOT NULL DEFAULT ''
+		)`,
+		`CREATE TABLE IF NOT EXISTS organization_quotas (
+			organization_id INTEGER NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+			product_code    TEXT NOT NULL REFERENCES products(code) ON DELETE CASCADE,
+			quota           INTEGER NOT NULL CHECK (quota >= 0),
+			PRIMARY KEY (organization_id, product_code)
+		)`,
+		`ALTER TABLE license_requests ADD COLUMN IF NOT EXISTS organization_id INTEGER
+			REFERENCES organizations(id) ON DELETE SET NULL`,
+		// Организация подтверждена кодом приглашения или администратором, а не только заявлена клиентом
+		`ALTER TABLE license_requests ADD COLUMN IF NOT EXISTS organization_verified BOOLEAN NOT NULL DEFAULT FALSE`,
+	}
+	for _, stmt := range stmts {
+		if _, err := db.DB.Exec(stmt); err != nil {
+			logging.Fatal("Error migrating organizations schema", "error", err)
+		}
+	}
+}
+
+// newInviteCode генерирует код приглашения
+func newInviteCode() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,174 @@
+package orgs
+
+import (
+	"database/sql"
+	"strings"
+
+	"example.com/licence-approval/server/pkg/db"
+)
+
+// List возвращает все организации с контактами и использованием квот
+func List() ([]Organization, error) {
+	rows, err := db.DB.Query(`
+		SELECT id, name, claim, invite_code, created_at FROM organizations ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []Organization
+	for rows.Next() {
+		var o Organization
+		if err := rows.Scan(&o.ID, &o.Name, &o.Claim, &o.InviteCode, &o.CreatedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range list {
+		if list[i].Contacts, err = contacts(list[i].ID); err != nil {
+			return nil, err
+		}
+		if list[i].Usage, err = usage(list[i].ID); err != nil {
+			return nil, err
+		}
+	}
+	return list, nil
+}
+
+// Names возвращает id → название для выпадающих списков
+func Names() (map[int]string, error) {
+	rows, err := db.DB.Query(`SELECT id, name FROM organizations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	names := make(map[int]string)
+	for rows.Next() {
+		var id int
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return nil, err
+		}
+		names[id] = name
+	}
+	return names, rows.Err()
+}
+
+func contacts(orgID int) ([]Contact, error) {
+	rows, err := db.DB.Query(`
+		SELECT id, name, email, role FROM organization_contacts
+		WHERE organization_id = $1 ORDER BY id`, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []Contact
+	for rows.Next() {
+		var c Contact
+		if err := rows.Scan(&c.ID, &c.Name, &c.Email, &c.Role); err != nil {
+			return nil, err
+		}
+		list = append(list, c)
+	}
+	return list, rows.Err()
+}
+
+func usage(orgID int) ([]QuotaUsage, error) {
+	rows, err := db.DB.Query(`
+		SELECT q.product_code, q.quota,
+			COUNT(r.id) FILTER (WHERE r.status = 'approved' AND NOT r.is_trial),
+			COUNT(r.id) FILTER (WHERE r.status = 'pending')
+		FROM organization_quotas q
+		LEFT JOIN license_requests r
+			ON r.organization_id = q.organization_id AND r.product_code = q.product_code
+		WHERE q.organization_id = $1
+		GROUP BY q.product_code, q.quota
+		ORDER BY q.product_code`, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []QuotaUsage
+	for rows.Next() {
+		var u QuotaUsage
+		if err := rows.Scan(&u.ProductCode, &u.Quota, &u.Used, &u.Pending); err != nil {
+			return nil, err
+		}
+		list = append(list, u)
+	}
+	return list, rows.Err()
+}
+
+// Resolve находит организацию по коду приглашения или, если его нет, по заявленному claim.
+// Возвращает 0, если организация не найдена.
+func Resolve(inviteCode, claim string) (int, error) {
+	var id int
+	var err error
+	switch {
+	case strings.TrimSpace(inviteCode) != "":
+		err = db.DB.QueryRow(`SELECT id FROM organizations WHERE invite_code = $1`,
+			strings.TrimSpace(inviteCode)).Scan(&id)
+	case strings.TrimSpace(claim) != "":
+		err = db.DB.QueryRow(`SELECT id FROM organizations WHERE claim = $1`,
+			strings.ToLower(strings.TrimSpace(claim))).Scan(&id)
+	default:
+		return 0, nil
+	}
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return id, err
+}
+
+// CheckQuota проверяет в транзакции, что организация может получить ещё одну лицензию продукта.
+// Строка квоты блокируется до конца транзакции, чтобы параллельные одобрения не превысили её.
+func CheckQuota(tx *sql.Tx, orgID int, productCode string) error {
+	var quota int
+	err := tx.QueryRow(`
+		SELECT quota FROM organization_quotas
+		WHERE organization_id = $1 AND product_code = $2 FOR UPDATE`, orgID, productCode).Scan(&quota)
+	if err == sql.ErrNoRows {
+		return ErrQuotaExceeded
+	}
+	if err != nil {
+		return err
+	}
+
+	var used int
+	err = tx.QueryRow(`
+		SELECT COUNT(*) FROM license_requests
+		WHERE organization_id = $1 AND product_code = $2 AND status = 'approved' AND NOT is_trial`,
+		orgID, productCode).Scan(&used)
+	if err != nil {
+		return err
+	}
+	if used >= quota {
+		return ErrQuotaExceeded
+	}
+	return nil
+}
+
+func productCodes() ([]string, error) {
+	rows, err := db.DB.Query(`SELECT code FROM products ORDER BY code`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var codes []string
+	for rows.Next() {
+		var code string
+		if err := rows.Scan(&code); err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+	return codes, rows.Err()
+}
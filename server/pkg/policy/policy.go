@@ -0,0 +1,290 @@
+// Package policy — декларативные правила автоматического решения по заявкам на лицензии.
+//
+// Правила проверяются по порядку, срабатывает первое подходящее. Внутри правила
+// все заданные условия должны выполняться одновременно, а значения внутри одного
+// условия перечисляются через «или». Пустое условие подходит под любую заявку.
+//
+// Почту, имя хоста и время клиент сообщает сам, поэтому одобрять заявку только
+// по ним нельзя: правило approve обязано проверять то, что сервер знает сам, —
+// IP-адрес (ip_ranges) или подтверждённую организацию (organizations).
+package policy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"path"
+	"strings"
+	"time"
+)
+
+// Action — решение по заявке
+type Action string
+
+const (
+	ActionApprove Action = "approve"
+	ActionReject  Action = "reject"
+	ActionManual  Action = "manual"
+)
+
+// Policy — набор правил из файла политики
+type Policy struct {
+	// DryRun — только записывать решение, не применяя его
+	DryRun   bool   `json:"dry_run"`
+	Timezone string `json:"timezone"`
+	Default  Action `json:"default"`
+	Rules    []Rule `json:"rules"`
+
+	loc *time.Location
+}
+
+// Rule — одно правило
+type Rule struct {
+	Name   string `json:"name"`
+	Action Action `json:"action"`
+	// TAG для автоматического одобрения; 0 — TAG продукта по умолчанию
+	Tag   int   `json:"tag"`
+	Match Match `json:"match"`
+
+	nets []*net.IPNet
+}
+
+// Match — условия правила
+type Match struct {
+	Products         []string    `json:"products"`
+	RequesterDomains []string    `json:"requester_domains"`
+	IPRanges         []string    `json:"ip_ranges"`
+	HostnamePatterns []string    `json:"hostname_patterns"` // шаблоны path.Match, без учёта регистра
+	Organizations    []string    `json:"organizations"`     // подтверждённые кодом приглашения или администратором
+	TimeOfDay        *TimeWindow `json:"time_of_day"`
+	Weekdays         []string    `json:"weekdays"` // "mon", "tue", ...
+}
+
+// TimeWindow — интервал времени суток "HH:MM"–"HH:MM"; может переходить через полночь
+type TimeWindow struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+
+	from, to int // минуты от начала суток
+}
+
+// Input — атрибуты заявки, по которым принимается решение
+type Input struct {
+	Product        string
+	RequesterEmail string
+	Hostname       string
+	IP             net.IP
+	Organization   string // подтверждённая организация; заявленная клиентом сюда не попадает
+	At             time.Time
+}
+
+// Decision — результат проверки
+type Decision struct {
+	Action Action
+	Rule   string // имя сработавшего правила; пусто — решение по умолчанию
+	Tag    int
+}
+
+// String — краткая запись решения для журнала и админки
+func (d Decision) String() string {
+	if d.Rule == "" {
+		return string(d.Action) + " (default)"
+	}
+	return string(d.Action) + " (" + d.Rule + ")"
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// Parse разбирает и проверяет политику (содержимое файла POLICY_FILE)
+func Parse(data []byte) (*Policy, error) {
+	var p Policy
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("parse policy: %w", err)
+	}
+	if err := p.compile(); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (p *Policy) compile() error {
+	if p.Default == "" {
+		p.Default = ActionManual
+	}
+	if !validAction(p.Default) {
+		return fmt.Errorf("invalid default action %q", p.Default)
+	}
+	if p.Default == ActionApprove {
+		return errors.New("default action cannot be approve: approve rules need ip_ranges or organizations")
+	}
+
+	p.loc = time.Local
+	if p.Timezone != "" {
+		loc, err := time.LoadLocation(p.Timezone)
+		if err != nil {
+			return fmt.Errorf("invalid timezone %q: %w", p.Timezone, err)
+		}
+		p.loc = loc
+	}
+
+	for i := range p.Rules {
+		r := &p.Rules[i]
+		if r.Name == "" {
+			r.Name = fmt.Sprintf("rule-%d", i+1)
+		}
+		if !validAction(r.Action) {
+			return fmt.Errorf("rule %s: invalid action %q", r.Name, r.Action)
+		}
+		if r.Tag < 0 || r.Tag > 1000 {
+			return fmt.Errorf("rule %s: TAG must be between 1 and 1000 (0 — product default)", r.Name)
+		}
+		for _, cidr := range r.Match.IPRanges {
+			_, n, err := net.ParseCIDR(cidr)
+			if err != nil {
+				return fmt.Errorf("rule %s: invalid IP range %q", r.Name, cidr)
+			}
+			r.nets = append(r.nets, n)
+		}
+		for _, pattern := range r.Match.HostnamePatterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("rule %s: invalid hostname pattern %q", r.Name, pattern)
+			}
+		}
+		for _, day := range r.Match.Weekdays {
+			if _, ok := weekdays[strings.ToLower(day)]; !ok {
+				return fmt.Errorf("rule %s: invalid weekday %q", r.Name, day)
+			}
+		}
+		if tw := r.Match.TimeOfDay; tw != nil {
+			var err error
+			if tw.from, err = parseClock(tw.From); err != nil {
+				return fmt.Errorf("rule %s: %w", r.Name, err)
+			}
+			if tw.to, err = parseClock(tw.To); err != nil {
+				return fmt.Errorf("rule %s: %w", r.Name, err)
+			}
+		}
+		if r.Action == ActionApprove && len(r.Match.IPRanges) == 0 && len(r.Match.Organizations) == 0 {
+			return fmt.Errorf("rule %s: approve rules need ip_ranges or organizations: other conditions are declared by the client", r.Name)
+		}
+	}
+	return nil
+}
+
+// Evaluate возвращает решение первого подходящего правила или решение по умолчанию
+func (p *Policy) Evaluate(in Input) Decision {
+	for i := range p.Rules {
+		r := &p.Rules[i]
+		if r.matches(in, p.loc) {
+			return Decision{Action: r.Action, Rule: r.Name, Tag: r.Tag}
+		}
+	}
+	return Decision{Action: p.Default}
+}
+
+func (r *Rule) matches(in Input, loc *time.Location) bool {
+	m := r.Match
+	if len(m.Products) > 0 && !containsFold(m.Products, in.Product) {
+		return false
+	}
+	if len(m.RequesterDomains) > 0 && !containsFold(m.RequesterDomains, emailDomain(in.RequesterEmail)) {
+		return false
+	}
+	if len(r.nets) > 0 && !inNets(r.nets, in.IP) {
+		return false
+	}
+	if len(m.HostnamePatterns) > 0 && !matchHostname(m.HostnamePatterns, in.Hostname) {
+		return false
+	}
+	if len(m.Organizations) > 0 && !containsFold(m.Organizations, in.Organization) {
+		return false
+	}
+
+	at := in.At.In(loc)
+	if len(m.Weekdays) > 0 {
+		ok := false
+		for _, day := range m.Weekdays {
+			if weekdays[strings.ToLower(day)] == at.Weekday() {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	if m.TimeOfDay != nil && !m.TimeOfDay.contains(at) {
+		return false
+	}
+	return true
+}
+
+func (tw *TimeWindow) contains(t time.Time) bool {
+	minute := t.Hour()*60 + t.Minute()
+	if tw.from <= tw.to {
+		return minute >= tw.from && minute < tw.to
+	}
+	// Интервал через полночь, например 22:00–06:00
+	return minute >= tw.from || minute < tw.to
+}
+
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func validAction(a Action) bool {
+	return a == ActionApprove || a == ActionReject || a == ActionManual
+}
+
+func containsFold(list []string, v string) bool {
+	if v == "" {
+		return false
+	}
+	for _, item := range list {
+		if strings.EqualFold(item, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func emailDomain(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return ""
+	}
+	return email[at+1:]
+}
+
+func inNets(nets []*net.IPNet, ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchHostname(patterns []string, hostname string) bool {
+	if hostname == "" {
+		return false
+	}
+	hostname = strings.ToLower(hostname)
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(strings.ToLower(pattern), hostname); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,237 @@
+package policy
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testPolicy = `{
+	"timezone": "UTC",
+	"default": "manual",
+	"rules": [
+		{"name": "blocked-subnet", "action": "reject",
+		 "match": {"ip_ranges": ["203.0.113.0/24"]}},
+		{"name": "acme-workhours", "action": "approve", "tag": 5,
+		 "match": {"products": ["editor"], "organizations": ["Acme"],
+		           "weekdays": ["mon", "tue", "wed", "thu", "fri"],
+		           "time_of_day": {"from": "09:00", "to": "18:00"}}},
+		{"name": "build-agents", "action": "approve",
+		 "match": {"hostname_patterns": ["build-*.ci.example.com"], "ip_ranges": ["10.0.0.0/8"]}},
+		{"name": "free-mail", "action": "reject",
+		 "match": {"requester_domains": ["gmail.com"]}},
+		{"name": "night", "action": "reject",
+		 "match": {"time_of_day": {"from": "22:00", "to": "06:00"}}}
+	]
+}`
+
+func mustTime(t *testing.T, s string) time.Time {
+	t.Helper()
+	at, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return at
+}
+
+func TestEvaluate(t *testing.T) {
+	p, err := Parse([]byte(testPolicy))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 2026-10-19 — понедельник, 2026-10-17 — суббота
+	tests := []struct {
+		name     string
+		in       Input
+		at       string
+		wantRule string
+		want     Action
+		wantTag  int
+	}{
+		{
+			name:     "organization request in working hours",
+			in:       Input{Product: "editor", Organization: "Acme", IP: net.ParseIP("192.0.2.10")},
+			at:       "2026-10-19T10:00:00Z",
+			wantRule: "acme-workhours", want: ActionApprove, wantTag: 5,
+		},
+		{
+			name:     "product and organization are case-insensitive",
+			in:       Input{Product: "EDITOR", Organization: "acme"},
+			at:       "2026-10-19T10:00:00Z",
+			wantRule: "acme-workhours", want: ActionApprove, wantTag: 5,
+		},
+		{
+			name:     "window start is inclusive",
+			in:       Input{Product: "editor", Organization: "Acme"},
+			at:       "2026-10-19T09:00:00Z",
+			wantRule: "acme-workhours", want: ActionApprove, wantTag: 5,
+		},
+		{
+			name: "window end is exclusive",
+			in:   Input{Product: "editor", Organization: "Acme"},
+			at:   "2026-10-19T18:00:00Z",
+			want: ActionManual,
+		},
+		{
+			name: "weekend does not match weekdays",
+			in:   Input{Product: "editor", Organization: "Acme"},
+			at:   "2026-10-17T10:00:00Z",
+			want: ActionManual,
+		},
+		{
+			name: "other organization",
+			in:   Input{Product: "editor", Organization: "Globex"},
+			at:   "2026-10-19T10:00:00Z",
+			want: ActionManual,
+		},
+		{
+			name: "unconfirmed organization",
+			in:   Input{Product: "editor", RequesterEmail: "anna@acme.example"},
+			at:   "2026-10-19T10:00:00Z",
+			want: ActionManual,
+		},
+		{
+			name: "other product",
+			in:   Input{Product: "viewer", Organization: "Acme"},
+			at:   "2026-10-19T10:00:00Z",
+			want: ActionManual,
+		},
+		{
+			name:     "first matching rule wins",
+			in:       Input{Product: "editor", Organization: "Acme", IP: net.ParseIP("203.0.113.7")},
+			at:       "2026-10-19T10:00:00Z",
+			wantRule: "blocked-subnet", want: ActionReject,
+		},
+		{
+			name:     "requester domain",
+			in:       Input{RequesterEmail: "anna@gmail.com"},
+			at:       "2026-10-19T10:00:00Z",
+			wantRule: "free-mail", want: ActionReject,
+		},
+		{
+			name:     "requester domain is case-insensitive",
+			in:       Input{RequesterEmail: "anna@GMail.COM"},
+			at:       "2026-10-19T10:00:00Z",
+			wantRule: "free-mail", want: ActionReject,
+		},
+		{
+			name: "subdomain is not the domain",
+			in:   Input{RequesterEmail: "anna@mail.gmail.com"},
+			at:   "2026-10-19T10:00:00Z",
+			want: ActionManual,
+		},
+		{
+			name:     "hostname glob and CIDR",
+			in:       Input{Hostname: "BUILD-7.ci.example.com", IP: net.ParseIP("10.1.2.3")},
+			at:       "2026-10-19T03:00:00Z",
+			wantRule: "build-agents", want: ActionApprove,
+		},
+		{
+			name:     "all conditions of a rule are required",
+			in:       Input{Hostname: "build-7.ci.example.com", IP: net.ParseIP("192.0.2.10")},
+			at:       "2026-10-19T03:00:00Z",
+			wantRule: "night", want: ActionReject,
+		},
+		{
+			name:     "window across midnight before midnight",
+			in:       Input{Product: "viewer"},
+			at:       "2026-10-19T23:30:00Z",
+			wantRule: "night", want: ActionReject,
+		},
+		{
+			name:     "window across midnight after midnight",
+			in:       Input{Product: "viewer"},
+			at:       "2026-10-20T05:59:00Z",
+			wantRule: "night", want: ActionReject,
+		},
+		{
+			name: "window across midnight end is exclusive",
+			in:   Input{Product: "viewer"},
+			at:   "2026-10-20T06:00:00Z",
+			want: ActionManual,
+		},
+		{
+			name:     "time is converted to the policy timezone",
+			in:       Input{Product: "editor", Organization: "Acme"},
+			at:       "2026-10-19T12:30:00+03:00",
+			wantRule: "acme-workhours", want: ActionApprove, wantTag: 5,
+		},
+		{
+			name:     "local evening is UTC night",
+			in:       Input{Product: "editor", Organization: "Acme"},
+			at:       "2026-10-19T20:00:00-05:00",
+			wantRule: "night", want: ActionReject,
+		},
+		{
+			name: "missing attributes match nothing",
+			in:   Input{},
+			at:   "2026-10-19T12:00:00Z",
+			want: ActionManual,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := tt.in
+			in.At = mustTime(t, tt.at)
+			got := p.Evaluate(in)
+			if got.Action != tt.want || got.Rule != tt.wantRule || got.Tag != tt.wantTag {
+				t.Errorf("Evaluate() = %+v, want {Action:%s Rule:%s Tag:%d}", got, tt.want, tt.wantRule, tt.wantTag)
+			}
+		})
+	}
+}
+
+func TestParseDefaults(t *testing.T) {
+	p, err := Parse([]byte(`{"rules": [{"action": "approve", "match": {"ip_ranges": ["10.0.0.0/8"]}}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Default != ActionManual {
+		t.Errorf("Default = %q, want %q", p.Default, ActionManual)
+	}
+	if p.Rules[0].Name != "rule-1" {
+		t.Errorf("rule name = %q, want rule-1", p.Rules[0].Name)
+	}
+
+	d := p.Evaluate(Input{IP: net.ParseIP("192.0.2.10"), At: time.Now()})
+	if got := d.String(); got != "manual (default)" {
+		t.Errorf("String() = %q", got)
+	}
+	d = p.Evaluate(Input{IP: net.ParseIP("10.1.2.3"), At: time.Now()})
+	if got := d.String(); got != "approve (rule-1)" {
+		t.Errorf("String() = %q", got)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy string
+		want   string
+	}{
+		{"malformed JSON", `{"rules": [`, "parse policy"},
+		{"default action", `{"default": "maybe"}`, "invalid default action"},
+		{"approve by default", `{"default": "approve"}`, "default action cannot be approve"},
+		{"approve without conditions", `{"rules": [{"action": "approve"}]}`, "need ip_ranges or organizations"},
+		{"approve on client-declared attributes", `{"rules": [{"action": "approve", "match": {
+			"products": ["editor"], "requester_domains": ["example.com"], "hostname_patterns": ["build-*"],
+			"weekdays": ["mon"], "time_of_day": {"from": "09:00", "to": "18:00"}}}]}`, "need ip_ranges or organizations"},
+		{"timezone", `{"timezone": "Mars/Olympus_Mons"}`, "invalid timezone"},
+		{"rule action", `{"rules": [{"action": "allow"}]}`, "invalid action"},
+		{"TAG range", `{"rules": [{"action": "approve", "tag": 1001}]}`, "TAG must be"},
+		{"IP range", `{"rules": [{"action": "reject", "match": {"ip_ranges": ["10.0.0.0/33"]}}]}`, "invalid IP range"},
+		{"hostname pattern", `{"rules": [{"action": "reject", "match": {"hostname_patterns": ["build-["]}}]}`, "invalid hostname pattern"},
+		{"weekday", `{"rules": [{"action": "reject", "match": {"weekdays": ["funday"]}}]}`, "invalid weekday"},
+		{"time of day", `{"rules": [{"action": "reject", "match": {"time_of_day": {"from": "25:00", "to": "06:00"}}}]}`, "invalid time of day"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.policy))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Parse() error = %v, want containing %q", err, tt.want)
+			}
+		})
+	}
+}
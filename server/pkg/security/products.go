@@ -0,0 +1,103 @@
+package security
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"example.com/licence-approval/server/pkg/metrics"
+	"example.com/licence-approval/server/pkg/tracing"
+)
+
+// productKeys — ключи подписи продуктов каталога по пути из products.signing_key_path
+var (
+	productKeysMu sync.Mutex
+	productKeys   = make(map[string]*rsa.PrivateKey)
+)
+
+// LoadProductKey читает RSA-ключ продукта из PEM (PKCS#1 или PKCS#8) и кэширует его по пути
+func LoadProductKey(path string) (*rsa.PrivateKey, error) {
+	productKeysMu.Lock()
+	defer productKeysMu.Unlock()
+
+	if key, ok := productKeys[path]; ok {
+		return key, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read key %s: %w", path, err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data in %s", path)
+	}
+
+	var key *rsa.PrivateKey
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		var parsed interface{}
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err == nil {
+			var ok bool
+			if key, ok = parsed.(*rsa.PrivateKey); !ok {
+				err = errors.New("not an RSA key")
+			}
+		}
+	default:
+		err = fmt.Errorf("unsupported PEM type %q", block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse key %s: %w", path, err)
+	}
+
+	productKeys[path] = key
+	return key, nil
+}
+
+// ProductPublicKeyPEM возвращает открытый ключ продукта (PEM, PKIX) — его
+// клиенты используют для проверки лицензий без сервера
+func ProductPublicKeyPEM(path string) ([]byte, error) {
+	key, err := LoadProductKey(path)
+	if err != nil {
+		return nil, err
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("marshal public key: %w", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), nil
+}
+
+// SignLicense подписывает payload ключом продукта из path (RSA PKCS#1 v1.5, SHA-256)
+// и возвращает подпись в base64
+func SignLicense(ctx context.Context, path string, payload []byte) (string, error) {
+	_, span := tracing.Start(ctx, "security.SignLicense")
+	sig, err := signLicense(path, payload)
+	tracing.End(span, err)
+	metrics.CountSigning(metrics.SigningLicense, err)
+	return sig, err
+}
+
+func signLicense(path string, payload []byte) (string, error) {
+	key, err := LoadProductKey(path)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.Sum256(payload)
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hash[:])
+	if err != nil {
+		return "", fmt.Errorf("sign: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(sig), nil
+}
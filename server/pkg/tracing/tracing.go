@@ -0,0 +1,102 @@
+// Package tracing — трассировка OpenTelemetry сервера лицензий: провайдер с
+// экспортом в OTLP или stdout, W3C trace context и помощники для спанов
+// обработчиков, запросов к БД и подписи.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Экспортёры спанов (TRACING_EXPORTER)
+const (
+	ExporterNone   = ""
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+)
+
+const instrumentationName = "example.com/licence-approval/server"
+
+var tracer = otel.Tracer(instrumentationName)
+
+// Configure настраивает W3C trace context и, если задан exporter, провайдер
+// спанов. Адрес и заголовки OTLP берутся из стандартных переменных
+// OTEL_EXPORTER_OTLP_*. Возвращённую функцию нужно вызвать при остановке,
+// чтобы отправить накопленные спаны.
+func Configure(exporter, service string) (shutdown func(context.Context) error, err error) {
+	// Пропагатор ставится и без экспорта: trace id клиента сохраняется в контексте запроса
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exp sdktrace.SpanExporter
+	switch exporter {
+	case ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterOTLP:
+		exp, err = otlptracehttp.New(context.Background())
+	case ExporterStdout:
+		exp, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	default:
+		return nil, fmt.Errorf("unknown exporter %q (want %s or %s)", exporter, ExporterOTLP, ExporterStdout)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("create %s exporter: %w", exporter, err)
+	}
+
+	// OTEL_SERVICE_NAME и OTEL_RESOURCE_ATTRIBUTES важнее имени по умолчанию
+	res, err := resource.New(context.Background(),
+		resource.WithAttributes(semconv.ServiceName(service)),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("tracing resource: %w", err)
+	}
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exp), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Start открывает внутренний спан name
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// StartDB открывает спан обращения к БД; operation — операция хранилища
+// (GetRequest, Approve), в которой может быть несколько запросов
+func StartDB(ctx context.Context, operation string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	attrs = append(attrs, semconv.DBSystemPostgreSQL, semconv.DBOperationName(operation))
+	return tracer.Start(ctx, "db "+operation, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+}
+
+// End завершает спан и отмечает его ошибкой, если err не nil. Ожидаемые
+// ошибки (заявка не найдена, лицензия не активна) сбоем не считаются.
+func End(span trace.Span, err error, expected ...error) {
+	if err != nil && !isExpected(err, expected) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func isExpected(err error, expected []error) bool {
+	for _, e := range expected {
+		if errors.Is(err, e) {
+			return true
+		}
+	}
+	return false
+}
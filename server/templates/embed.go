@@ -3,17 +3,80 @@ package templates
 import (
 	"embed"
 	"html/template"
-	"log"
+	"io"
+	"net/http"
+	"time"
+
+	"example.com/licence-approval/server/pkg/i18n"
+	"example.com/licence-approval/server/pkg/logging"
 )
 
 //go:embed *.html
 var tmplFS embed.FS
 
-// Парсит все шаблоны из embed FS
-func ParseTemplates() *template.Template {
-	tmpl, err := template.ParseFS(tmplFS, "*.html")
+// funcs — функции, доступные шаблонам; t, lang и duration подменяются для каждого языка
+var funcs = template.FuncMap{
+	"asset":     assetURL,
+	"integrity": assetIntegrity,
+	"layout":    newLayout,
+	"languages": func() []string { return i18n.Supported },
+	"t":         func(key string, args ...interface{}) string { return key },
+	"lang":      func() string { return "" },
+	"duration":  func(d time.Duration) string { return d.String() },
+}
+
+// Set — шаблоны админки, разобранные для каждого поддерживаемого языка
+type Set struct {
+	byLang map[string]*template.Template
+}
+
+// Парсит все шаблоны из embed FS и готовит копию для каждого языка каталога
+func ParseTemplates() *Set {
+	base, err := template.New("").Funcs(funcs).ParseFS(tmplFS, "*.html")
 	if err != nil {
-		log.Fatalf("Error parsing templates: %v", err)
+		logging.Fatal("Error parsing templates", "error", err)
+	}
+	s := &Set{byLang: make(map[string]*template.Template)}
+	for _, lang := range i18n.Supported {
+		lang := lang
+		t, err := base.Clone()
+		if err != nil {
+			logging.Fatal("Error cloning templates", "lang", lang, "error", err)
+		}
+		s.byLang[lang] = t.Funcs(template.FuncMap{
+			"t":        func(key string, args ...interface{}) string { return i18n.T(lang, key, args...) },
+			"lang":     func() string { return lang },
+			"duration": func(d time.Duration) string { return i18n.Duration(lang, d) },
+		})
 	}
-	return tmpl
+	return s
+}
+
+// ExecuteTemplate выполняет шаблон name на языке lang (неизвестный язык — язык админки по умолчанию)
+func (s *Set) ExecuteTemplate(w io.Writer, lang, name string, data interface{}) error {
+	t, ok := s.byLang[lang]
+	if !ok {
+		t = s.byLang[i18n.DefaultAdmin]
+	}
+	return t.ExecuteTemplate(w, name, data)
+}
+
+// Render отдаёт страницу админки на языке, выбранном для запроса
+func (s *Set) Render(w http.ResponseWriter, r *http.Request, name string, data interface{}) error {
+	lang := i18n.AdminLanguage(r)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Language", lang)
+	w.Header().Add("Vary", "Accept-Language, Cookie")
+	return s.ExecuteTemplate(w, lang, name, data)
+}
+
+// Layout — параметры общего каркаса страниц админки (шаблоны header и footer
+// из layout.html): заголовок и активный пункт меню
+type Layout struct {
+	Title string
+	Nav   string
+}
+
+func newLayout(title, nav string) Layout {
+	return Layout{Title: title, Nav: nav}
 }
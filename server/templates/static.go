@@ -0,0 +1,97 @@
+package templates
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"embed"
+	"encoding/base64"
+	"encoding/hex"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"path"
+	"strings"
+
+	"example.com/licence-approval/server/pkg/logging"
+)
+
+// StaticPrefix — URL, по которому отдаются статические файлы админки
+const StaticPrefix = "/static/"
+
+//go:embed static
+var staticFS embed.FS
+
+// asset — статический файл: версия для URL и хэш для Subresource Integrity
+type asset struct {
+	version   string
+	integrity string
+}
+
+// assets собираются при запуске: файлы встроены в бинарник и не меняются
+var assets = loadAssets()
+
+func loadAssets() map[string]asset {
+	m := make(map[string]asset)
+	err := fs.WalkDir(staticFS, "static", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := staticFS.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(data)
+		sri := sha512.Sum384(data)
+		m[strings.TrimPrefix(p, "static/")] = asset{
+			version:   hex.EncodeToString(sum[:8]),
+			integrity: "sha384-" + base64.StdEncoding.EncodeToString(sri[:]),
+		}
+		return nil
+	})
+	if err != nil {
+		logging.Fatal("Error loading static assets", "error", err)
+	}
+	return m
+}
+
+// assetURL — URL файла с версией: при обновлении файла меняется и URL,
+// поэтому браузер может кэшировать его без перепроверки
+func assetURL(name string) string {
+	a, ok := assets[name]
+	if !ok {
+		slog.Warn("Unknown static asset", "name", name)
+		return StaticPrefix + name
+	}
+	return StaticPrefix + name + "?v=" + a.version
+}
+
+// assetIntegrity — значение атрибута integrity для файла
+func assetIntegrity(name string) string {
+	return assets[name].integrity
+}
+
+// StaticHandler отдаёт встроенные статические файлы. Запросы с актуальной
+// версией (?v=) кэшируются на год, остальные — с перепроверкой по ETag.
+func StaticHandler() http.Handler {
+	sub, err := fs.Sub(staticFS, "static")
+	if err != nil {
+		logging.Fatal("Error opening static assets", "error", err)
+	}
+	files := http.FileServer(http.FS(sub))
+	return http.StripPrefix(StaticPrefix, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		a, ok := assets[path.Clean(strings.TrimPrefix(r.URL.Path, "/"))]
+		if !ok {
+			// Листинг каталогов не отдаём
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("ETag", `"`+a.version+`"`)
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		if r.URL.Query().Get("v") == a.version {
+			w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+		} else {
+			w.Header().Set("Cache-Control", "no-cache")
+		}
+		files.ServeHTTP(w, r)
+	}))
+}